@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Wraps middlewareAuth so that only admin users holding the admin scope get through
+func (cfg *apiConfig) middlewareAdmin(handler authedHandler) http.HandlerFunc {
+	return cfg.middlewareAuth(scopeAdmin, func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if !user.IsAdmin {
+			respondWithError(w, 403, "This user is not an admin")
+			return
+		}
+
+		handler(w, r, user)
+	})
+}
+
+type AdminUser struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username,omitempty"`
+	IsAdmin    bool       `json:"is_admin"`
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+func databaseUserToAdminUser(user database.User) AdminUser {
+	var disabledAt *time.Time
+	if user.DisabledAt.Valid {
+		disabledAt = &user.DisabledAt.Time
+	}
+
+	return AdminUser{
+		ID:         user.ID,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+		Name:       user.Name,
+		Username:   user.Username.String,
+		IsAdmin:    user.IsAdmin,
+		DisabledAt: disabledAt,
+	}
+}
+
+func (cfg *apiConfig) adminGetUsersHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	dbUsers, err := cfg.DB.GetAllUsers(r.Context())
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Users"))
+		return
+	}
+
+	users := []AdminUser{}
+	for _, u := range dbUsers {
+		users = append(users, databaseUserToAdminUser(u))
+	}
+
+	respondWithJSON(w, 200, users)
+}
+
+func (cfg *apiConfig) adminSetUserDisabled(disabled bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		id, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			respondWithError(w, 400, "Error getting user ID: "+err.Error())
+			return
+		}
+
+		if id == user.ID {
+			respondWithError(w, 400, "Admins cannot disable themselves")
+			return
+		}
+
+		target, err := cfg.DB.GetUserById(r.Context(), id)
+		if err != nil {
+			respondWithApiError(w, dbError(err, "Error Getting User"))
+			return
+		}
+
+		disabledAt := sql.NullTime{Time: time.Now(), Valid: disabled}
+		err = cfg.DB.SetUserDisabled(r.Context(), database.SetUserDisabledParams{
+			DisabledAt: disabledAt,
+			UpdatedAt:  time.Now(),
+			ID:         target.ID,
+		})
+		if err != nil {
+			respondWithApiError(w, dbError(err, "Error Updating User"))
+			return
+		}
+
+		target.DisabledAt = disabledAt
+		respondWithJSON(w, 200, databaseUserToAdminUser(target))
+	}
+}
+
+// Looks up the feed in the path
+func (cfg *apiConfig) getFeedFromPath(w http.ResponseWriter, r *http.Request) (database.Feed, bool) {
+	id, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting feed ID: "+err.Error())
+		return database.Feed{}, false
+	}
+
+	feed, err := cfg.DB.GetFeedById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Feed"))
+		return database.Feed{}, false
+	}
+
+	return feed, true
+}
+
+func (cfg *apiConfig) adminSetFeedDisabled(disabled bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		feed, ok := cfg.getFeedFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		err := cfg.DB.SetFeedDisabled(r.Context(), database.SetFeedDisabledParams{
+			DisabledAt: sql.NullTime{Time: time.Now(), Valid: disabled},
+			UpdatedAt:  time.Now(),
+			ID:         feed.ID,
+		})
+		if err != nil {
+			respondWithApiError(w, dbError(err, "Error Updating Feed"))
+			return
+		}
+
+		w.WriteHeader(200)
+	}
+}
+
+// Fetches the feed straight away instead of waiting for its turn
+func (cfg *apiConfig) adminRefreshFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getFeedFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	cfg.startFetch(feed)
+
+	w.WriteHeader(202)
+}
+
+func (cfg *apiConfig) adminDeleteFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getFeedFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteFeed(r.Context(), feed.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Feed"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) adminFetcherStatusHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.Fetcher.mu.Lock()
+	defer cfg.Fetcher.mu.Unlock()
+
+	type res struct {
+		LastRunStartedAt  time.Time            `json:"last_run_started_at"`
+		LastRunFinishedAt time.Time            `json:"last_run_finished_at"`
+		FeedsFetched      int                  `json:"feeds_fetched"`
+		PostsCreated      int                  `json:"posts_created"`
+		Errors            map[uuid.UUID]string `json:"errors"`
+	}
+
+	respondWithJSON(w, 200, res{
+		LastRunStartedAt:  cfg.Fetcher.LastRunStartedAt,
+		LastRunFinishedAt: cfg.Fetcher.LastRunFinishedAt,
+		FeedsFetched:      cfg.Fetcher.FeedsFetched,
+		PostsCreated:      cfg.Fetcher.PostsCreated,
+		Errors:            cfg.Fetcher.Errors,
+	})
+}
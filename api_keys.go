@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Number of leading characters kept in the clear so users can tell keys apart
+const apiKeyPrefixLength = 8
+
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+	scopeAdmin = "admin"
+)
+
+// What a key gets when the caller doesn't ask for anything specific
+var defaultScopes = []string{scopeRead, scopeWrite}
+
+// Sessions act on behalf of the user, the same as a default key, plus admin
+// for admins
+func sessionScopes(user database.User) []string {
+	if user.IsAdmin {
+		return append([]string{scopeAdmin}, defaultScopes...)
+	}
+	return defaultScopes
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type ApiKey struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+}
+
+func databaseApiKeyToApiKey(apiKey database.ApiKey) ApiKey {
+	var lastUsedAt *time.Time
+	if apiKey.LastUsedAt.Valid {
+		lastUsedAt = &apiKey.LastUsedAt.Time
+	}
+
+	var expiresAt *time.Time
+	if apiKey.ExpiresAt.Valid {
+		expiresAt = &apiKey.ExpiresAt.Time
+	}
+
+	return ApiKey{
+		ID:         apiKey.ID,
+		CreatedAt:  apiKey.CreatedAt,
+		LastUsedAt: lastUsedAt,
+		ExpiresAt:  expiresAt,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+	}
+}
+
+// Keys are long and random, so a plain SHA-256 is enough to keep them out of the database
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Pulls the key out of an "Authorization: ApiKey <key>" header
+func getApiKeyFromHeader(r *http.Request) (string, string) {
+	headerAuth := r.Header.Get("Authorization")
+	if headerAuth == "" {
+		return "", "Authorization header missing"
+	}
+
+	apiKey := strings.TrimPrefix(headerAuth, "ApiKey ")
+	if apiKey == headerAuth {
+		return "", "Malformed Token"
+	}
+
+	return apiKey, ""
+}
+
+func optionalTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func generateApiKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Generates a new key for the user and stores its hash. The plaintext key is
+// only ever returned from here.
+func issueApiKey(ctx context.Context, q *database.Queries, userID uuid.UUID, name string, expiresAt sql.NullTime, scopes []string) (string, database.ApiKey, error) {
+	key, err := generateApiKey()
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	apiKey, err := q.CreateApiKey(ctx, database.CreateApiKeyParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		Name:      name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashApiKey(key),
+		ExpiresAt: expiresAt,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	return key, apiKey, nil
+}
+
+func (cfg *apiConfig) createApiKeyHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name      string     `json:"name" validate:"max=100"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Scopes    []string   `json:"scopes"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	if b.Scopes == nil {
+		b.Scopes = defaultScopes
+	}
+
+	// A key can only hand out scopes it holds itself
+	current := authFromContext(r.Context())
+	for _, scope := range b.Scopes {
+		if scope != scopeRead && scope != scopeWrite && scope != scopeAdmin {
+			respondWithError(w, 400, "Unknown scope: "+scope)
+			return
+		}
+		if !hasScope(current.Scopes, scope) {
+			respondWithApiError(w, errMissingScope(scope))
+			return
+		}
+	}
+
+	key, apiKey, err := issueApiKey(r.Context(), cfg.DB, user.ID, b.Name, optionalTime(b.ExpiresAt), b.Scopes)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Creating Api Key"))
+		return
+	}
+
+	type res struct {
+		ApiKey
+		Key string `json:"key"`
+	}
+
+	respondWithJSON(w, 200, res{ApiKey: databaseApiKeyToApiKey(apiKey), Key: key})
+}
+
+func (cfg *apiConfig) getApiKeysHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	dbKeys, err := cfg.DB.GetApiKeysByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Api Keys"))
+		return
+	}
+
+	keys := []ApiKey{}
+	for _, key := range dbKeys {
+		keys = append(keys, databaseApiKeyToApiKey(key))
+	}
+
+	respondWithJSON(w, 200, keys)
+}
+
+func (cfg *apiConfig) deleteApiKeyHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, err := uuid.Parse(r.PathValue("apiKeyID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting api key ID: "+err.Error())
+		return
+	}
+
+	apiKey, err := cfg.DB.GetApiKeyById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Api Key"))
+		return
+	}
+
+	if apiKey.UserID != user.ID {
+		respondWithApiError(w, errForbidden("This user does not own the given api key"))
+		return
+	}
+
+	err = cfg.DB.DeleteApiKey(r.Context(), apiKey.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Api Key"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Replaces the key used for this request. The old key keeps working for the
+// grace period so clients can be moved over without downtime.
+func (cfg *apiConfig) rotateApiKeyHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		GracePeriodSeconds int        `json:"grace_period_seconds" validate:"min=0"`
+		ExpiresAt          *time.Time `json:"expires_at"`
+	}
+
+	// The body is optional, an empty one decodes as {}
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	auth := authFromContext(r.Context())
+	if auth.ApiKey == nil {
+		respondWithError(w, 400, "Only an ApiKey can be rotated")
+		return
+	}
+
+	oldKey := *auth.ApiKey
+	var key string
+	var apiKey database.ApiKey
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		key, apiKey, err = issueApiKey(r.Context(), q, user.ID, oldKey.Name, optionalTime(b.ExpiresAt), oldKey.Scopes)
+		if err != nil {
+			return dbError(err, "Error Creating Api Key")
+		}
+
+		// Never extend a key that was already due to expire sooner
+		graceEnd := time.Now().Add(time.Duration(b.GracePeriodSeconds) * time.Second)
+		if !oldKey.ExpiresAt.Valid || graceEnd.Before(oldKey.ExpiresAt.Time) {
+			oldKey.ExpiresAt = sql.NullTime{Time: graceEnd, Valid: true}
+			err = q.ExpireApiKey(r.Context(), database.ExpireApiKeyParams{
+				ExpiresAt: oldKey.ExpiresAt,
+				ID:        oldKey.ID,
+			})
+			if err != nil {
+				return dbError(err, "Error Expiring Api Key")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Rotating Api Key"))
+		return
+	}
+
+	type res struct {
+		ApiKey
+		Key    string `json:"key"`
+		OldKey ApiKey `json:"old_key"`
+	}
+
+	respondWithJSON(w, 200, res{
+		ApiKey: databaseApiKeyToApiKey(apiKey),
+		Key:    key,
+		OldKey: databaseApiKeyToApiKey(oldKey),
+	})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Posts are canonicalised this many at a time
+const backfillBatchSize = 1000
+
+// Fills in canonical URLs for rows from before they existed, using the same
+// canonicalURL the app looks them up with. Rows that turn out to be the same
+// feed or post are merged into the oldest one. Works before or after 024 and
+// is safe to run more than once, including after canonicalURL changes.
+func (cfg *apiConfig) backfillCanonicalURLs(ctx context.Context) error {
+	err := cfg.backfillFeedCanonicalURLs(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.backfillAliasCanonicalURLs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return cfg.backfillPostCanonicalURLs(ctx)
+}
+
+// Feeds go oldest first. One whose canonical URL already belongs to another
+// feed is the same feed, so the newer of the two is merged into the older.
+func (cfg *apiConfig) backfillFeedCanonicalURLs(ctx context.Context) error {
+	feeds, err := cfg.DB.GetFeedUrls(ctx)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	merged := map[uuid.UUID]bool{}
+	for _, feed := range feeds {
+		canonical := canonicalURL(feed.Url)
+		if merged[feed.ID] || canonical == feed.CanonicalUrl {
+			continue
+		}
+
+		err := cfg.inTx(ctx, func(q *database.Queries) error {
+			existing, err := q.GetFeedByCanonicalUrl(ctx, canonical)
+			if err == nil {
+				from, to := feed.ID, existing.ID
+				if feed.CreatedAt.Before(existing.CreatedAt) {
+					from, to = existing.ID, feed.ID
+				}
+
+				err := mergeFeed(ctx, q, from, to)
+				if errors.Is(err, errPrivateFeedMerge) {
+					log.Printf("Not merging feed %s into %s, one of them is private", from, to)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				merged[from] = true
+				log.Printf("Merged duplicate feed %s of %s into %s", from, canonical, to)
+
+				if to != feed.ID {
+					return nil
+				}
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			err = q.SetFeedCanonicalUrl(ctx, database.SetFeedCanonicalUrlParams{
+				CanonicalUrl: canonical,
+				ID:           feed.ID,
+			})
+			if err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Canonicalised %d feed URLs", count)
+
+	return nil
+}
+
+// Aliases are looked up by canonical URL, so they're rewritten the same way.
+// One that now matches a feed's own URL is dropped, the feed is found first.
+func (cfg *apiConfig) backfillAliasCanonicalURLs(ctx context.Context) error {
+	aliases, err := cfg.DB.GetFeedUrlAliases(ctx)
+	if err != nil {
+		return err
+	}
+
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		kept := map[string]uuid.UUID{}
+		for _, alias := range aliases {
+			canonical := canonicalURL(alias.Url)
+
+			err := q.DeleteFeedUrlAlias(ctx, alias.Url)
+			if err != nil {
+				return err
+			}
+
+			feed, err := q.GetFeedByCanonicalUrl(ctx, canonical)
+			if err == nil {
+				if feed.ID != alias.FeedID {
+					log.Printf("Dropped alias %s of feed %s, it is the URL of feed %s", alias.Url, alias.FeedID, feed.ID)
+				}
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			// Oldest first, so the alias that was there first wins
+			if feedID, ok := kept[canonical]; ok {
+				if feedID != alias.FeedID {
+					log.Printf("Dropped alias %s of feed %s, it collides with an alias of feed %s", alias.Url, alias.FeedID, feedID)
+				}
+				continue
+			}
+			kept[canonical] = alias.FeedID
+
+			err = q.CreateFeedUrlAlias(ctx, database.CreateFeedUrlAliasParams{
+				Url:       canonical,
+				FeedID:    alias.FeedID,
+				CreatedAt: alias.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Posts go a batch at a time in ID order. A duplicate's stars are moved to
+// the older post, its saved search matches and fingerprints are deleted
+// with it.
+func (cfg *apiConfig) backfillPostCanonicalURLs(ctx context.Context) error {
+	count := 0
+	after := uuid.Nil
+	for {
+		posts, err := cfg.DB.GetPostUrlsAfter(ctx, database.GetPostUrlsAfterParams{ID: after, Limit: backfillBatchSize})
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		err = cfg.inTx(ctx, func(q *database.Queries) error {
+			deleted := map[uuid.UUID]bool{}
+			for _, post := range posts {
+				canonical := canonicalURL(post.Url)
+				if deleted[post.ID] || canonical == post.CanonicalUrl {
+					continue
+				}
+
+				existing, err := q.GetPostByCanonicalUrl(ctx, canonical)
+				if err == nil {
+					from, to := post.ID, existing.ID
+					if post.CreatedAt.Before(existing.CreatedAt) {
+						from, to = existing.ID, post.ID
+					}
+
+					err := q.MovePostStars(ctx, database.MovePostStarsParams{ToPostID: to, FromPostID: from})
+					if err != nil {
+						return err
+					}
+					err = q.DeletePost(ctx, from)
+					if err != nil {
+						return err
+					}
+					deleted[from] = true
+					log.Printf("Merged duplicate post %s of %s into %s", from, canonical, to)
+
+					if to != post.ID {
+						continue
+					}
+				} else if !errors.Is(err, sql.ErrNoRows) {
+					return err
+				}
+
+				err = q.SetPostCanonicalUrl(ctx, database.SetPostCanonicalUrlParams{
+					CanonicalUrl: canonical,
+					ID:           post.ID,
+				})
+				if err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		after = posts[len(posts)-1].ID
+	}
+	log.Printf("Canonicalised %d post URLs", count)
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"math/bits"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+const (
+	// Fingerprints at most this many bits apart are the same story. Feed
+	// items are short, so this is looser than SimHash's usual 3. Candidates
+	// are found by simHashBands, which only finds everything up to 6.
+	maxClusterDistance = 6
+	// Only posts published this close together are compared
+	clusterWindow = 48 * time.Hour
+)
+
+var (
+	htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+	wordPattern    = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// 64-bit SimHash over the words of the title and text. Similar texts get
+// fingerprints that differ in only a few bits. Title words count double since
+// syndicated copies tend to keep the headline and trim the body.
+func simHash(title, description string) int64 {
+	var weights [64]int
+
+	add := func(text string, weight int) {
+		words := wordPattern.FindAllString(strings.ToLower(htmlTagPattern.ReplaceAllString(text, " ")), -1)
+		for _, word := range words {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			for bit := 0; bit < 64; bit++ {
+				if sum&(1<<bit) != 0 {
+					weights[bit] += weight
+				} else {
+					weights[bit] -= weight
+				}
+			}
+		}
+	}
+	add(title, 2)
+	add(description, 1)
+
+	var fingerprint uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return int64(fingerprint)
+}
+
+// Keys for looking up fingerprints that might be close to this one, one for
+// each pair of its eight bytes: the pair's index, then the two bytes. Two
+// fingerprints at most 6 bits apart differ in at most 6 bytes, so at least
+// two bytes match and they share that pair's key.
+func simHashBands(fingerprint int64) []int32 {
+	var keys []int32
+	pair := int32(0)
+	for i := 0; i < 8; i++ {
+		for j := i + 1; j < 8; j++ {
+			a := int32(uint64(fingerprint) >> (8 * i) & 0xff)
+			b := int32(uint64(fingerprint) >> (8 * j) & 0xff)
+			keys = append(keys, pair<<16|a<<8|b)
+			pair++
+		}
+	}
+	return keys
+}
+
+func hammingDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// Fingerprints a freshly stored post and puts it in the cluster of the
+// closest post from another feed published around the same time, or a new
+// cluster of its own. Only posts sharing a band key are compared.
+func (cfg *apiConfig) clusterNewPost(ctx context.Context, post database.Post) {
+	fingerprint := simHash(post.Title, post.Description)
+	bands := simHashBands(fingerprint)
+
+	candidates, err := cfg.DB.GetFingerprintCandidates(ctx, database.GetFingerprintCandidatesParams{
+		BandKeys:        bands,
+		PublishedAfter:  post.PublishedAt.Add(-clusterWindow),
+		PublishedBefore: post.PublishedAt.Add(clusterWindow),
+		FeedID:          post.FeedID,
+	})
+	if err != nil {
+		log.Println("Error getting post fingerprints: " + err.Error())
+		return
+	}
+
+	clusterID := post.ID
+	best := maxClusterDistance + 1
+	for _, candidate := range candidates {
+		if d := hammingDistance(fingerprint, candidate.Fingerprint); d < best {
+			best = d
+			clusterID = candidate.ClusterID
+		}
+	}
+
+	err = cfg.inTx(ctx, func(q *database.Queries) error {
+		err := q.CreatePostFingerprint(ctx, database.CreatePostFingerprintParams{
+			PostID:      post.ID,
+			Fingerprint: fingerprint,
+			ClusterID:   clusterID,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreatePostFingerprintBands(ctx, database.CreatePostFingerprintBandsParams{
+			PostID:   post.ID,
+			BandKeys: bands,
+		})
+	})
+	if err != nil {
+		log.Println("Error storing post fingerprint: " + err.Error())
+	}
+}
+
+type PostLink struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type CollapsedPost struct {
+	database.Post
+	// The same story in the user's other feeds
+	Duplicates []PostLink `json:"duplicates"`
+}
+
+// Collapses a timeline to one post per story. It runs as a pagePosts filter,
+// so pages are collapsed before they're cut and remember the clusters already
+// shown, and a story never comes back on a later page.
+type clusterCollapser struct {
+	ctx       context.Context
+	db        *database.Queries
+	seen      map[uuid.UUID]bool
+	clusterOf map[uuid.UUID]uuid.UUID
+}
+
+func (cfg *apiConfig) newClusterCollapser(ctx context.Context) *clusterCollapser {
+	return &clusterCollapser{
+		ctx:       ctx,
+		db:        cfg.DB,
+		seen:      map[uuid.UUID]bool{},
+		clusterOf: map[uuid.UUID]uuid.UUID{},
+	}
+}
+
+// Posts from before clustering existed are clusters of one
+func (c *clusterCollapser) cluster(post database.Post) uuid.UUID {
+	if clusterID, ok := c.clusterOf[post.ID]; ok {
+		return clusterID
+	}
+	return post.ID
+}
+
+// Keeps the first post of each cluster, in timeline order
+func (c *clusterCollapser) filter(posts []database.Post) ([]database.Post, error) {
+	ids := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	rows, err := c.db.GetPostClusters(c.ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		c.clusterOf[row.PostID] = row.ClusterID
+	}
+
+	kept := []database.Post{}
+	for _, post := range posts {
+		clusterID := c.cluster(post)
+		if c.seen[clusterID] {
+			continue
+		}
+		c.seen[clusterID] = true
+		kept = append(kept, post)
+	}
+	return kept, nil
+}
+
+// Lists the rest of each post's cluster alongside it, leaving out copies the
+// user has hidden from their timeline or by a hide rule
+func (c *clusterCollapser) collapse(userID uuid.UUID, rules []compiledRule, posts []database.Post) ([]CollapsedPost, error) {
+	collapsed := make([]CollapsedPost, 0, len(posts))
+	clusterIDs := make([]uuid.UUID, 0, len(posts))
+	index := map[uuid.UUID]int{}
+	for i, post := range posts {
+		clusterID := c.cluster(post)
+		clusterIDs = append(clusterIDs, clusterID)
+		index[clusterID] = i
+		collapsed = append(collapsed, CollapsedPost{Post: post, Duplicates: []PostLink{}})
+	}
+
+	members, err := c.db.GetClusterPostsForUser(c.ctx, database.GetClusterPostsForUserParams{
+		UserID:     userID,
+		ClusterIds: clusterIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		i := index[member.ClusterID]
+		if member.ID == collapsed[i].ID {
+			continue
+		}
+		post := database.Post{
+			ID:           member.ID,
+			CreatedAt:    member.CreatedAt,
+			UpdatedAt:    member.UpdatedAt,
+			Title:        member.Title,
+			Url:          member.Url,
+			Description:  member.Description,
+			PublishedAt:  member.PublishedAt,
+			FeedID:       member.FeedID,
+			CanonicalUrl: member.CanonicalUrl,
+		}
+		if isHidden(rules, post) {
+			continue
+		}
+		collapsed[i].Duplicates = append(collapsed[i].Duplicates, PostLink{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			FeedID:      post.FeedID,
+			PublishedAt: post.PublishedAt,
+		})
+	}
+
+	return collapsed, nil
+}
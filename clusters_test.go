@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b int64
+		want int
+	}{
+		{"equal", 0x5555, 0x5555, 0},
+		{"one bit", 0, 1, 1},
+		{"low byte", 0, 0xff, 8},
+		{"sign bit", 0, -1 << 63, 1},
+		{"all bits", 0, -1, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hammingDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("hammingDistance(%#x, %#x) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	const (
+		title       = "City council approves new budget for public transit expansion"
+		description = "The council voted seven to two on Tuesday to fund three new bus lines and extend the light rail to the airport by 2028."
+	)
+
+	tests := []struct {
+		name               string
+		title, description string
+		same               bool
+	}{
+		{"identical", title, description, true},
+		{"case and punctuation", "CITY COUNCIL approves new budget, for public transit expansion!", description, true},
+		{"html markup", title, "<p>The council voted <b>seven to two</b> on Tuesday to fund three new bus lines and extend the light rail to the airport by 2028.</p>", true},
+		{"trimmed body", title, "The council voted seven to two on Tuesday to fund three new bus lines.", true},
+		{"different story", "Local bakery wins national award for sourdough bread", "Judges praised the crust and the open crumb of the loaf, which has been baked the same way since 1952.", false},
+	}
+
+	want := simHash(title, description)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := simHash(tt.title, tt.description)
+			d := hammingDistance(want, got)
+			if tt.same && d > maxClusterDistance {
+				t.Errorf("distance %d, want at most %d", d, maxClusterDistance)
+			}
+			if !tt.same && d <= maxClusterDistance {
+				t.Errorf("distance %d, want more than %d", d, maxClusterDistance)
+			}
+		})
+	}
+}
+
+func TestSimHashBands(t *testing.T) {
+	keys := simHashBands(0x0123456789abcdef)
+	if len(keys) != 28 {
+		t.Fatalf("got %d keys, want 28", len(keys))
+	}
+
+	// Migration 027 computes the pair index as i*(15-i)/2 + j-i-1
+	pair := 0
+	for i := 0; i < 8; i++ {
+		for j := i + 1; j < 8; j++ {
+			if got := int(keys[pair] >> 16); got != i*(15-i)/2+j-i-1 {
+				t.Errorf("pair (%d, %d) has index %d, want %d", i, j, got, i*(15-i)/2+j-i-1)
+			}
+			if got := keys[pair] & 0xffff; got != int32(0xef-0x22*i)<<8|int32(0xef-0x22*j) {
+				t.Errorf("pair (%d, %d) has bytes %#x", i, j, got)
+			}
+			pair++
+		}
+	}
+
+	tests := []struct {
+		name  string
+		flips int
+	}{
+		{"identical", 0},
+		{"one bit", 1},
+		{"max distance", maxClusterDistance},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n := 0; n < 1000; n++ {
+				a := int64(rng.Uint64())
+				b := a
+				for _, bit := range rng.Perm(64)[:tt.flips] {
+					b ^= 1 << bit
+				}
+				if !shareKey(simHashBands(a), simHashBands(b)) {
+					t.Fatalf("%#x and %#x are %d bits apart but share no key", a, b, tt.flips)
+				}
+			}
+		})
+	}
+
+	// One flipped bit in every byte leaves nothing to match on
+	if shareKey(simHashBands(0), simHashBands(0x0101010101010101)) {
+		t.Error("fingerprints differing in every byte share a key")
+	}
+}
+
+func shareKey(a, b []int32) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+const (
+	credentialsBasic      = "basic"
+	credentialsBearer     = "bearer"
+	credentialsQueryParam = "query_param"
+)
+
+// A secret a private feed is fetched with. Which fields are used depends on
+// the kind: username and password for basic, token for bearer, param and
+// value for query_param.
+type feedCredentials struct {
+	Kind     string `json:"kind" validate:"required"`
+	Username string `json:"username" validate:"max=200"`
+	Password string `json:"password" validate:"max=200"`
+	Token    string `json:"token" validate:"max=4000"`
+	Param    string `json:"param" validate:"max=100"`
+	Value    string `json:"value" validate:"max=4000"`
+}
+
+// Validation on top of the struct tags, with field names under prefix
+func (c feedCredentials) check(prefix string) []fieldError {
+	var fields []fieldError
+	for _, field := range validateStruct(&c) {
+		field.Field = prefix + field.Field
+		fields = append(fields, field)
+	}
+
+	missing := func(name string) {
+		fields = append(fields, fieldError{Field: prefix + name, Message: "is required for " + c.Kind + " credentials"})
+	}
+	switch c.Kind {
+	case credentialsBasic:
+		if c.Username == "" {
+			missing("username")
+		}
+	case credentialsBearer:
+		if c.Token == "" {
+			missing("token")
+		}
+		if strings.ContainsAny(c.Token, "\r\n") {
+			fields = append(fields, fieldError{Field: prefix + "token", Message: "must not contain line breaks"})
+		}
+	case credentialsQueryParam:
+		if c.Param == "" {
+			missing("param")
+		}
+		if c.Value == "" {
+			missing("value")
+		}
+	case "":
+	default:
+		fields = append(fields, fieldError{Field: prefix + "kind", Message: "must be one of basic, bearer, query_param"})
+	}
+	return fields
+}
+
+func (c *feedCredentials) apply(req *http.Request) {
+	switch c.Kind {
+	case credentialsBasic:
+		req.SetBasicAuth(c.Username, c.Password)
+	case credentialsBearer:
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case credentialsQueryParam:
+		query := req.URL.Query()
+		query.Set(c.Param, c.Value)
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// Takes a query param secret back out of a URL we fetched, so it never ends
+// up stored as the feed's URL
+func (c *feedCredentials) stripFrom(u *url.URL) {
+	if c == nil || c.Kind != credentialsQueryParam {
+		return
+	}
+	query := u.Query()
+	query.Del(c.Param)
+	u.RawQuery = query.Encode()
+}
+
+// Hides a query param secret in error messages, which quote the URL
+func (c *feedCredentials) redact(msg string) string {
+	if c == nil || c.Kind != credentialsQueryParam {
+		return msg
+	}
+	msg = strings.ReplaceAll(msg, url.QueryEscape(c.Value), "REDACTED")
+	return strings.ReplaceAll(msg, c.Value, "REDACTED")
+}
+
+// CREDENTIALS_KEY is 32 bytes, base64 encoded. Without it private feeds are
+// turned off rather than storing credentials we can't protect.
+func credentialsKeyFromEnv() ([]byte, error) {
+	v := os.Getenv("CREDENTIALS_KEY")
+	if v == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("CREDENTIALS_KEY: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("CREDENTIALS_KEY: must decode to 32 bytes")
+	}
+	return key, nil
+}
+
+var errCredentialsUnavailable = &apiError{
+	Status:  http.StatusServiceUnavailable,
+	Code:    codeInternal,
+	Message: "Private feeds are not enabled on this server",
+}
+
+// Encrypts with AES-256-GCM. The random nonce goes in front of the ciphertext.
+func (cfg *apiConfig) sealCredentials(c feedCredentials) ([]byte, error) {
+	gcm, err := cfg.credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (cfg *apiConfig) openCredentials(sealed []byte) (*feedCredentials, error) {
+	gcm, err := cfg.credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("stored credentials are truncated")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("stored credentials could not be decrypted, was CREDENTIALS_KEY changed?")
+	}
+
+	var c feedCredentials
+	if err := json.Unmarshal(plaintext, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (cfg *apiConfig) credentialsCipher() (cipher.AEAD, error) {
+	if len(cfg.CredentialsKey) == 0 {
+		return nil, errCredentialsUnavailable
+	}
+
+	block, err := aes.NewCipher(cfg.CredentialsKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Forgets every credential saved for the feed, its owner's and its followers'.
+// Used when the feed moves to another host, which they were never meant for.
+// With no credentials left the feed is no longer private.
+func clearFeedCredentials(ctx context.Context, q *database.Queries, feedID uuid.UUID) error {
+	err := q.ClearFeedFetchCredentials(ctx, database.ClearFeedFetchCredentialsParams{
+		UpdatedAt: time.Now(),
+		FeedID:    feedID,
+	})
+	if err != nil {
+		return dbError(err, "Error Clearing Fetch Credentials")
+	}
+
+	err = q.DeleteFeedFollowCredentialsByFeedId(ctx, feedID)
+	if err != nil {
+		return dbError(err, "Error Deleting Credentials")
+	}
+
+	return nil
+}
+
+// The secret itself is never sent back
+type FeedFollowCredentials struct {
+	FeedFollowID uuid.UUID `json:"feed_follow_id"`
+	Kind         string    `json:"kind"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Saves the credentials that give this user access to a private feed. They're
+// only saved if fetching the feed with them works, and the feed keeps being
+// fetched with its owner's credentials either way.
+func (cfg *apiConfig) putFeedFollowCredentialsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	feed, err := cfg.DB.GetFeedById(r.Context(), feedFollow.FeedID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Feed"))
+		return
+	}
+
+	var b feedCredentials
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+	if fields := b.check(""); len(fields) > 0 {
+		respondWithApiError(w, &apiError{Status: 400, Code: codeValidation, Message: "Request body failed validation", Fields: fields})
+		return
+	}
+
+	opts, err := cfg.fetchOptionsForFeed(r.Context(), feed.ID)
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Getting Fetch Settings"))
+		return
+	}
+	if opts.Credentials == nil {
+		respondWithApiError(w, &apiError{Status: 409, Code: codeConflict, Message: "This feed is not private, it doesn't need credentials"})
+		return
+	}
+
+	opts.Credentials = &b
+	_, _, err = cfg.FeedClient.fetch(r.Context(), feed.Url, opts)
+	if err != nil {
+		respondWithApiError(w, &apiError{Status: 400, Code: codeValidation, Message: "Could not fetch the feed with these credentials: " + err.Error()})
+		return
+	}
+
+	sealed, err := cfg.sealCredentials(b)
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Encrypting Credentials"))
+		return
+	}
+
+	credentials, err := cfg.DB.UpsertFeedFollowCredentials(r.Context(), database.UpsertFeedFollowCredentialsParams{
+		FeedFollowID: feedFollow.ID,
+		Kind:         b.Kind,
+		Encrypted:    sealed,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Saving Credentials"))
+		return
+	}
+
+	respondWithJSON(w, 200, FeedFollowCredentials{
+		FeedFollowID: credentials.FeedFollowID,
+		Kind:         credentials.Kind,
+		UpdatedAt:    credentials.UpdatedAt,
+	})
+}
+
+func (cfg *apiConfig) deleteFeedFollowCredentialsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteFeedFollowCredentials(r.Context(), feedFollow.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Credentials"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
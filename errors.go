@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Stable values for the "code" field of error responses. Clients switch on
+// these, so don't rename them.
+const (
+	codeInvalidBody     = "invalid_body"
+	codeBodyTooLarge    = "body_too_large"
+	codeValidation      = "validation_failed"
+	codeUnauthorized    = "unauthorized"
+	codeInvalidApiKey   = "invalid_api_key"
+	codeApiKeyExpired   = "api_key_expired"
+	codeInvalidToken    = "invalid_token"
+	codeForbidden       = "forbidden"
+	codeMissingScope    = "missing_scope"
+	codeAccountDisabled = "account_disabled"
+	codeNotFound        = "not_found"
+	codeConflict        = "conflict"
+	codeRateLimited     = "rate_limited"
+	codeInternal        = "internal_error"
+)
+
+// An error that knows how it should be reported to the client
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+	// Per-field problems, for validation failures
+	Fields []fieldError
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// Default code for errors that don't need anything more specific
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return codeValidation
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusRequestEntityTooLarge:
+		return codeBodyTooLarge
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	default:
+		return codeInternal
+	}
+}
+
+// For errors that may already be an *apiError, e.g. ones returned from inside
+// inTx. Anything else is treated as a database error.
+func asApiError(err error, msg string) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return dbError(err, msg)
+}
+
+func errInvalidBody(err error) *apiError {
+	return &apiError{Status: 400, Code: codeInvalidBody, Message: "Invalid request body: " + err.Error()}
+}
+
+func errForbidden(msg string) *apiError {
+	return &apiError{Status: 403, Code: codeForbidden, Message: msg}
+}
+
+func errMissingScope(scope string) *apiError {
+	return &apiError{Status: 403, Code: codeMissingScope, Message: "Credentials are missing the " + scope + " scope"}
+}
+
+// Works out what a database error means for the client. msg says what we were
+// doing and what we were looking for, e.g. "Error Getting Feed".
+func dbError(err error, msg string) *apiError {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &apiError{Status: 404, Code: codeNotFound, Message: msg + ": not found"}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return &apiError{Status: 409, Code: codeConflict, Message: msg + ": " + pqErr.Detail}
+		case "foreign_key_violation":
+			return &apiError{Status: 404, Code: codeNotFound, Message: msg + ": " + pqErr.Detail}
+		case "invalid_text_representation", "check_violation", "not_null_violation":
+			return &apiError{Status: 400, Code: codeValidation, Message: msg + ": " + pqErr.Message}
+		}
+	}
+
+	return &apiError{Status: 500, Code: codeInternal, Message: msg + ": " + err.Error()}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title string `xml:"title"`
+	} `xml:"head"`
+	Body []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XmlUrl   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// Builds an OPML document from the user's follows. Followed feeds sit inside
+// an outline per folder, and anything not in a folder sits at the top level.
+func buildOpml(title string, follows []database.GetFeedFollowsByUserIdRow, folders []database.Folder, entries []database.FolderFeedFollow) opml {
+	feedOutlines := map[uuid.UUID]opmlOutline{}
+	for _, follow := range follows {
+		name := follow.FeedName
+		if follow.CustomTitle.Valid {
+			name = follow.CustomTitle.String
+		}
+		feedOutlines[follow.ID] = opmlOutline{Text: name, Title: name, Type: "rss", XmlUrl: follow.FeedUrl}
+	}
+
+	inFolder := map[uuid.UUID]bool{}
+	folderOutlines := map[uuid.UUID][]opmlOutline{}
+	for _, entry := range entries {
+		if outline, ok := feedOutlines[entry.FeedFollowID]; ok {
+			folderOutlines[entry.FolderID] = append(folderOutlines[entry.FolderID], outline)
+			inFolder[entry.FeedFollowID] = true
+		}
+	}
+
+	doc := opml{Version: "2.0"}
+	doc.Head.Title = title
+	for _, folder := range folders {
+		doc.Body = append(doc.Body, opmlOutline{Text: folder.Name, Outlines: folderOutlines[folder.ID]})
+	}
+	for _, follow := range follows {
+		if !inFolder[follow.ID] {
+			doc.Body = append(doc.Body, feedOutlines[follow.ID])
+		}
+	}
+
+	return doc
+}
+
+// Starred posts are loaded this many at a time
+const exportPageSize = 500
+
+// Streams a zip of everything we hold about the user. Each part is written
+// straight into the response as soon as it is loaded, and starred posts a page
+// at a time, so the archive is never held in memory as a whole.
+func (cfg *apiConfig) exportUserHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := cfg.DB.GetFeedFollowsByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Feed Follows"))
+		return
+	}
+
+	folders, err := cfg.DB.GetFoldersByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folders"))
+		return
+	}
+
+	entries, err := cfg.DB.GetFolderFeedFollowsByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folder Feed Follows"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="rss-export.zip"`)
+	w.WriteHeader(200)
+
+	archive := zip.NewWriter(w)
+
+	// Once the first byte is out the status can't change. Aborting the
+	// response instead of closing the archive makes the download fail, rather
+	// than leaving a valid zip with parts missing.
+	writeEntry := func(name string, write func(io.Writer) error) {
+		entry, err := archive.Create(name)
+		if err == nil {
+			err = write(entry)
+		}
+		if err != nil {
+			log.Println("Error writing export entry " + name + " for user " + user.ID.String() + ": " + err.Error())
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	writeJSON := func(name string, load func() (any, error)) {
+		writeEntry(name, func(entry io.Writer) error {
+			data, err := load()
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(entry)
+			enc.SetIndent("", "  ")
+			return enc.Encode(data)
+		})
+	}
+
+	writeJSON("profile.json", func() (any, error) {
+		type profile struct {
+			ID        uuid.UUID `json:"id"`
+			CreatedAt time.Time `json:"created_at"`
+			UpdatedAt time.Time `json:"updated_at"`
+			Name      string    `json:"name"`
+			Username  string    `json:"username,omitempty"`
+		}
+		return profile{
+			ID:        user.ID,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+			Name:      user.Name,
+			Username:  user.Username.String,
+		}, nil
+	})
+
+	writeEntry("follows.opml", func(entry io.Writer) error {
+		if _, err := io.WriteString(entry, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(entry)
+		enc.Indent("", "  ")
+		return enc.Encode(buildOpml(user.Name+"'s feeds", follows, folders, entries))
+	})
+
+	writeJSON("feed_follows.json", func() (any, error) {
+		res := []FeedFollow{}
+		for _, row := range follows {
+			res = append(res, databaseFeedFollowToFeedFollow(database.FeedFollow{
+				ID:                 row.ID,
+				UserID:             row.UserID,
+				FeedID:             row.FeedID,
+				CreatedAt:          row.CreatedAt,
+				UpdatedAt:          row.UpdatedAt,
+				CustomTitle:        row.CustomTitle,
+				HiddenFromTimeline: row.HiddenFromTimeline,
+				Pinned:             row.Pinned,
+			}, row.FeedName))
+		}
+		return res, nil
+	})
+
+	writeJSON("folders.json", func() (any, error) {
+		// Like saved searches, last_read_at is the read state
+		unreadCounts, err := cfg.getFolderUnreadCounts(r.Context(), user.ID)
+		feedFollowIDs := map[uuid.UUID][]uuid.UUID{}
+		for _, entry := range entries {
+			feedFollowIDs[entry.FolderID] = append(feedFollowIDs[entry.FolderID], entry.FeedFollowID)
+		}
+		res := []Folder{}
+		for _, folder := range folders {
+			res = append(res, databaseFolderToFolder(folder, feedFollowIDs[folder.ID], unreadCounts[folder.ID]))
+		}
+		return res, err
+	})
+
+	// Written as a JSON array a page at a time, however many stars there are
+	writeEntry("starred.json", func(entry io.Writer) error {
+		sep := "["
+		for offset := int32(0); ; offset += exportPageSize {
+			posts, err := cfg.DB.GetStarredPostsByUser(r.Context(), database.GetStarredPostsByUserParams{
+				UserID: user.ID,
+				Limit:  exportPageSize,
+				Offset: offset,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, post := range posts {
+				data, err := json.MarshalIndent(post, "  ", "  ")
+				if err != nil {
+					return err
+				}
+				if _, err := io.WriteString(entry, sep+"\n  "+string(data)); err != nil {
+					return err
+				}
+				sep = ","
+			}
+
+			if len(posts) < exportPageSize {
+				break
+			}
+		}
+
+		end := "\n]\n"
+		if sep == "[" {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(entry, end)
+		return err
+	})
+
+	writeJSON("rules.json", func() (any, error) {
+		rules, err := cfg.DB.GetRulesByUserId(r.Context(), user.ID)
+		res := []Rule{}
+		for _, rule := range rules {
+			res = append(res, databaseRuleToRule(rule))
+		}
+		return res, err
+	})
+
+	writeJSON("saved_searches.json", func() (any, error) {
+		// last_read_at is the only read state we keep
+		return cfg.getSavedSearches(r.Context(), user.ID)
+	})
+
+	if err := archive.Close(); err != nil {
+		log.Println("Error finishing export for user " + user.ID.String() + ": " + err.Error())
+		panic(http.ErrAbortHandler)
+	}
+}
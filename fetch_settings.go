@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Header values and credentials are never sent back, they often hold secrets
+type FeedFetchSettings struct {
+	HeaderNames     []string  `json:"header_names"`
+	CredentialsKind string    `json:"credentials_kind,omitempty"`
+	HasCredentials  bool      `json:"has_credentials"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func databaseFeedFetchSettingsToFeedFetchSettings(settings database.FeedFetchSetting) FeedFetchSettings {
+	var headers map[string]string
+	json.Unmarshal(settings.Headers, &headers)
+
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return FeedFetchSettings{
+		HeaderNames:     names,
+		CredentialsKind: settings.CredentialsKind.String,
+		HasCredentials:  settings.Credentials != nil,
+		UpdatedAt:       settings.UpdatedAt,
+	}
+}
+
+// Headers are stored as they are, so anything that looks like it carries a
+// secret has to go in credentials, which are encrypted
+var credentialHeaderWords = []string{"auth", "cookie", "token", "key", "secret", "session", "password"}
+
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range credentialHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the extra headers and credentials sent when fetching the feed.
+// Credentials make the feed private to its owner and to followers whose own
+// credentials work on it.
+func (cfg *apiConfig) putFeedFetchSettingsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getOwnedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	type body struct {
+		Headers     map[string]string `json:"headers"`
+		Credentials *feedCredentials  `json:"credentials"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	var fields []fieldError
+	for name, value := range b.Headers {
+		if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+			fields = append(fields, fieldError{Field: "headers", Message: "invalid header name " + name})
+		} else if isCredentialHeader(name) {
+			fields = append(fields, fieldError{Field: "headers", Message: "header " + name + " looks like a secret, set it as credentials instead"})
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			fields = append(fields, fieldError{Field: "headers", Message: "header " + name + " has a line break in its value"})
+		}
+	}
+	if b.Credentials != nil {
+		fields = append(fields, b.Credentials.check("credentials.")...)
+	}
+	if len(fields) > 0 {
+		respondWithApiError(w, &apiError{Status: 400, Code: codeValidation, Message: "Request body failed validation", Fields: fields})
+		return
+	}
+
+	if b.Headers == nil {
+		b.Headers = map[string]string{}
+	}
+	headers, err := json.Marshal(b.Headers)
+	if err != nil {
+		respondWithApiError(w, errInvalidBody(err))
+		return
+	}
+
+	var kind sql.NullString
+	var sealed []byte
+	if b.Credentials != nil {
+		kind = sql.NullString{String: b.Credentials.Kind, Valid: true}
+		sealed, err = cfg.sealCredentials(*b.Credentials)
+		if err != nil {
+			respondWithApiError(w, asApiError(err, "Error Encrypting Credentials"))
+			return
+		}
+	}
+
+	settings, err := cfg.DB.UpsertFeedFetchSettings(r.Context(), database.UpsertFeedFetchSettingsParams{
+		FeedID:          feed.ID,
+		Headers:         headers,
+		CredentialsKind: kind,
+		Credentials:     sealed,
+		UpdatedAt:       time.Now(),
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Updating Fetch Settings"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseFeedFetchSettingsToFeedFetchSettings(settings))
+}
+
+func (cfg *apiConfig) getFeedFetchSettingsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getOwnedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	settings, err := cfg.DB.GetFeedFetchSettings(r.Context(), feed.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		settings = database.FeedFetchSetting{FeedID: feed.ID}
+	} else if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Fetch Settings"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseFeedFetchSettingsToFeedFetchSettings(settings))
+}
+
+func (cfg *apiConfig) deleteFeedFetchSettingsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getOwnedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteFeedFetchSettings(r.Context(), feed.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Fetch Settings"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How the fetcher talks to feed servers. Everything can be set from the
+// environment, see fetcherConfigFromEnv.
+type fetcherConfig struct {
+	ConnectTimeout time.Duration
+	// Whole request, including reading the body
+	Timeout      time.Duration
+	UserAgent    string
+	MaxBodyBytes int64
+	// PEM file of extra CAs to trust on top of the system pool
+	CABundle string
+	// Feeds fetched at once, and at once from any single host
+	Workers         int
+	HostConcurrency int
+	// Minimum time between starting requests to the same host
+	HostDelay time.Duration
+}
+
+var defaultFetcherConfig = fetcherConfig{
+	ConnectTimeout:  10 * time.Second,
+	Timeout:         30 * time.Second,
+	UserAgent:       "bootdev-rss/1.0 (+https://github.com/saubuny/bootdev-rss)",
+	MaxBodyBytes:    10 << 20,
+	Workers:         4,
+	HostConcurrency: 2,
+	HostDelay:       time.Second,
+}
+
+// Reads FETCH_CONNECT_TIMEOUT, FETCH_TIMEOUT and FETCH_HOST_DELAY (Go
+// durations like "10s"), FETCH_USER_AGENT, FETCH_MAX_BODY_BYTES,
+// FETCH_CA_BUNDLE, FETCH_WORKERS and FETCH_HOST_CONCURRENCY. Proxies come from
+// the usual HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func fetcherConfigFromEnv() (fetcherConfig, error) {
+	cfg := defaultFetcherConfig
+
+	for name, dst := range map[string]*time.Duration{
+		"FETCH_CONNECT_TIMEOUT": &cfg.ConnectTimeout,
+		"FETCH_TIMEOUT":         &cfg.Timeout,
+		"FETCH_HOST_DELAY":      &cfg.HostDelay,
+	} {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %v", name, err)
+			}
+			*dst = d
+		}
+	}
+
+	if v := os.Getenv("FETCH_USER_AGENT"); v != "" {
+		cfg.UserAgent = v
+	}
+
+	if v := os.Getenv("FETCH_MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("FETCH_MAX_BODY_BYTES: must be a positive number of bytes")
+		}
+		cfg.MaxBodyBytes = n
+	}
+
+	for name, dst := range map[string]*int{
+		"FETCH_WORKERS":          &cfg.Workers,
+		"FETCH_HOST_CONCURRENCY": &cfg.HostConcurrency,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return cfg, fmt.Errorf("%s: must be a positive number", name)
+			}
+			*dst = n
+		}
+	}
+
+	cfg.CABundle = os.Getenv("FETCH_CA_BUNDLE")
+
+	return cfg, nil
+}
+
+type feedClient struct {
+	client       *http.Client
+	userAgent    string
+	maxBodyBytes int64
+	workers      int
+	hosts        *hostLimiter
+}
+
+func newFeedClient(cfg fetcherConfig) (*feedClient, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in CA bundle " + cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Leaving Accept-Encoding alone lets the transport ask for gzip and
+	// decompress it for us
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	return &feedClient{
+		client:       &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		userAgent:    cfg.UserAgent,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		workers:      cfg.Workers,
+		hosts:        newHostLimiter(cfg.HostConcurrency, cfg.HostDelay),
+	}, nil
+}
+
+// Per-feed additions to the request
+type fetchOptions struct {
+	Headers     map[string]string
+	Credentials *feedCredentials
+}
+
+// Works out the headers and credentials to fetch a feed with, from the
+// settings its owner gave it
+func (cfg *apiConfig) fetchOptionsForFeed(ctx context.Context, feedID uuid.UUID) (fetchOptions, error) {
+	var opts fetchOptions
+
+	settings, err := cfg.DB.GetFeedFetchSettings(ctx, feedID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return opts, err
+	}
+	if len(settings.Headers) > 0 {
+		if err := json.Unmarshal(settings.Headers, &opts.Headers); err != nil {
+			return opts, fmt.Errorf("Bad fetch headers: %v", err)
+		}
+	}
+
+	if settings.Credentials == nil {
+		return opts, nil
+	}
+	opts.Credentials, err = cfg.openCredentials(settings.Credentials)
+	return opts, err
+}
+
+// Fetches and parses the feed, applying any per-feed headers and credentials.
+// If every redirect on the way was permanent (301 or 308), movedTo is the URL
+// we ended up at.
+func (c *feedClient) fetch(ctx context.Context, feedUrl string, opts fetchOptions) (rss Rss, movedTo string, err error) {
+	// Query param secrets end up in the URL, so keep them out of errors
+	defer func() {
+		if err == nil {
+			return
+		}
+		if redacted := opts.Credentials.redact(err.Error()); redacted != err.Error() {
+			err = errors.New(redacted)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedUrl, nil)
+	if err != nil {
+		return Rss{}, "", err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	if opts.Credentials != nil {
+		opts.Credentials.apply(req)
+	}
+
+	// The host we hold a slot for, which changes as we follow redirects
+	held := req.URL.Host
+	err = c.hosts.acquire(ctx, held)
+	if err != nil {
+		return Rss{}, "", err
+	}
+	defer func() {
+		if held != "" {
+			c.hosts.release(held)
+		}
+	}()
+
+	// The client is shared, so track this request's redirects on a copy
+	permanent := true
+	client := *c.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		code := req.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			permanent = false
+		}
+
+		// The client copies our headers onto every hop, but the feed's custom
+		// ones were only meant for its own host
+		if req.URL.Host != via[0].URL.Host {
+			for name := range opts.Headers {
+				req.Header.Del(name)
+			}
+		}
+
+		// A redirect to another host counts against that host's limit
+		if host := req.URL.Host; host != held {
+			c.hosts.release(held)
+			held = ""
+			if err := c.hosts.acquire(req.Context(), host); err != nil {
+				return err
+			}
+			held = host
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Rss{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			// Whoever answered is who wants the break, which may be past a redirect
+			until := time.Now().Add(d)
+			c.hosts.backOff(resp.Request.URL.Host, until)
+			return Rss{}, "", &hostBackoffError{
+				msg:   fmt.Sprintf("Status error: %v, retry after %v", resp.StatusCode, d),
+				until: until,
+			}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Rss{}, "", fmt.Errorf("Status error: %v", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodyBytes+1))
+	if err != nil {
+		return Rss{}, "", fmt.Errorf("Read body: %v", err)
+	}
+	if int64(len(data)) > c.maxBodyBytes {
+		return Rss{}, "", fmt.Errorf("Feed larger than %d bytes", c.maxBodyBytes)
+	}
+
+	err = xml.Unmarshal(data, &rss)
+	if err != nil {
+		return rss, "", err
+	}
+
+	final := *resp.Request.URL
+	opts.Credentials.stripFrom(&final)
+	if finalUrl := final.String(); permanent && finalUrl != feedUrl {
+		movedTo = finalUrl
+	}
+
+	return rss, movedTo, nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+type Folder struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	LastReadAt    time.Time   `json:"last_read_at"`
+	Name          string      `json:"name"`
+	FeedFollowIDs []uuid.UUID `json:"feed_follow_ids"`
+	UnreadCount   int64       `json:"unread_count"`
+}
+
+func databaseFolderToFolder(folder database.Folder, feedFollowIDs []uuid.UUID, unreadCount int64) Folder {
+	if feedFollowIDs == nil {
+		feedFollowIDs = []uuid.UUID{}
+	}
+
+	return Folder{
+		ID:            folder.ID,
+		CreatedAt:     folder.CreatedAt,
+		UpdatedAt:     folder.UpdatedAt,
+		LastReadAt:    folder.LastReadAt,
+		Name:          folder.Name,
+		FeedFollowIDs: feedFollowIDs,
+		UnreadCount:   unreadCount,
+	}
+}
+
+// Counts the posts fetched into each of the user's folders since it was last
+// marked read, keyed by folder ID
+func (cfg *apiConfig) getFolderUnreadCounts(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int64, error) {
+	rows, err := cfg.DB.GetFolderUnreadCounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[uuid.UUID]int64{}
+	for _, row := range rows {
+		counts[row.ID] = row.UnreadCount
+	}
+	return counts, nil
+}
+
+func (cfg *apiConfig) createFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name string `json:"name" validate:"required,max=100"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	now := time.Now()
+	folder, err := cfg.DB.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Name:       b.Name,
+		LastReadAt: now,
+	})
+
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Creating Folder"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseFolderToFolder(folder, nil, 0))
+}
+
+func (cfg *apiConfig) getFoldersHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	dbFolders, err := cfg.DB.GetFoldersByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folders"))
+		return
+	}
+
+	entries, err := cfg.DB.GetFolderFeedFollowsByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folder Feed Follows"))
+		return
+	}
+
+	unreadCounts, err := cfg.getFolderUnreadCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Unread Counts"))
+		return
+	}
+
+	feedFollowIDs := map[uuid.UUID][]uuid.UUID{}
+	for _, entry := range entries {
+		feedFollowIDs[entry.FolderID] = append(feedFollowIDs[entry.FolderID], entry.FeedFollowID)
+	}
+
+	folders := []Folder{}
+	for _, folder := range dbFolders {
+		folders = append(folders, databaseFolderToFolder(folder, feedFollowIDs[folder.ID], unreadCounts[folder.ID]))
+	}
+
+	respondWithJSON(w, 200, folders)
+}
+
+// Looks up the folder in the path and checks that the user owns it
+func (cfg *apiConfig) getOwnedFolder(w http.ResponseWriter, r *http.Request, user database.User) (database.Folder, bool) {
+	id, err := uuid.Parse(r.PathValue("folderID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting folder ID: "+err.Error())
+		return database.Folder{}, false
+	}
+
+	folder, err := cfg.DB.GetFolderById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folder"))
+		return database.Folder{}, false
+	}
+
+	if folder.UserID != user.ID {
+		respondWithApiError(w, errForbidden("This user does not own the given folder"))
+		return database.Folder{}, false
+	}
+
+	return folder, true
+}
+
+func (cfg *apiConfig) renameFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	type body struct {
+		Name string `json:"name" validate:"required,max=100"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	folder, err := cfg.DB.RenameFolder(r.Context(), database.RenameFolderParams{
+		Name:      b.Name,
+		UpdatedAt: time.Now(),
+		ID:        folder.ID,
+	})
+
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Renaming Folder"))
+		return
+	}
+
+	feedFollowIDs, err := cfg.DB.GetFolderFeedFollowIds(r.Context(), folder.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Folder Feed Follows"))
+		return
+	}
+
+	unreadCounts, err := cfg.getFolderUnreadCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Unread Counts"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseFolderToFolder(folder, feedFollowIDs, unreadCounts[folder.ID]))
+}
+
+func (cfg *apiConfig) markFolderReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.MarkFolderRead(r.Context(), database.MarkFolderReadParams{
+		LastReadAt: time.Now(),
+		ID:         folder.ID,
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Marking Folder Read"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) deleteFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteFolder(r.Context(), folder.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Folder"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Looks up the feed follow in the path and checks that the user owns it
+func (cfg *apiConfig) getOwnedFeedFollow(w http.ResponseWriter, r *http.Request, user database.User) (database.FeedFollow, bool) {
+	id, err := uuid.Parse(r.PathValue("feedFollowID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting feed follow ID: "+err.Error())
+		return database.FeedFollow{}, false
+	}
+
+	feedFollow, err := cfg.DB.GetFeedFollowById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Feed Follow"))
+		return database.FeedFollow{}, false
+	}
+
+	if feedFollow.UserID != user.ID {
+		respondWithApiError(w, errForbidden("This user does not own the given feed follow"))
+		return database.FeedFollow{}, false
+	}
+
+	return feedFollow, true
+}
+
+func (cfg *apiConfig) addFeedFollowToFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.AddFeedFollowToFolder(r.Context(), database.AddFeedFollowToFolderParams{
+		FolderID:     folder.ID,
+		FeedFollowID: feedFollow.ID,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Adding Feed Follow To Folder"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) removeFeedFollowFromFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.RemoveFeedFollowFromFolder(r.Context(), database.RemoveFeedFollowFromFolderParams{
+		FolderID:     folder.ID,
+		FeedFollowID: feedFollow.ID,
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Removing Feed Follow From Folder"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) getFolderPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folder, ok := cfg.getOwnedFolder(w, r, user)
+	if !ok {
+		return
+	}
+
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	rules, err := cfg.compiledRulesFor(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Rules"))
+		return
+	}
+
+	posts, err := pagePosts(limit, offset, func(limit, offset int32) ([]database.Post, error) {
+		return cfg.DB.GetFolderPosts(r.Context(), database.GetFolderPostsParams{
+			FolderID: folder.ID,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}, hideFilter(rules))
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Posts"))
+		return
+	}
+
+	respondWithJSON(w, 200, posts)
+}
@@ -1,15 +1,16 @@
 package main
 
 import (
-	"encoding/json"
-	"io"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/saubuny/bootdev-rss/internal/database"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -25,7 +26,9 @@ func errorHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	type body struct {
-		Name string `json:"name"`
+		Name     string `json:"name" validate:"required,max=200"`
+		Username string `json:"username" validate:"max=64"`
+		Password string `json:"password" validate:"maxbytes=72"`
 	}
 
 	type res struct {
@@ -33,56 +36,184 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 		Name      string    `json:"name"`
+		Username  string    `json:"username,omitempty"`
 		ApiKey    string    `json:"api_key"`
 	}
 
 	var name body
-	req, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(req, &name)
+	if apiErr := decodeBody(w, r, &name); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
 
+	// Password logins are optional, but need both halves
+	var username, passwordHash sql.NullString
+	if name.Username != "" || name.Password != "" {
+		if name.Username == "" {
+			respondWithError(w, 400, "Username missing")
+			return
+		}
+		if len(name.Password) < minPasswordLength {
+			respondWithError(w, 400, "Password must be at least 8 characters")
+			return
+		}
+
+		var err error
+		username = sql.NullString{String: name.Username, Valid: true}
+		passwordHash, err = hashPassword(name.Password)
+		if err != nil {
+			respondWithError(w, 500, "Error Hashing Password: "+err.Error())
+			return
+		}
+	}
+
+	var user database.User
+	var apiKey string
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		user, err = q.CreateUser(r.Context(), database.CreateUserParams{
+			ID:           uuid.New(),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			Name:         name.Name,
+			Username:     username,
+			PasswordHash: passwordHash,
+		})
+		if err != nil {
+			return dbError(err, "Error Creating User")
+		}
+
+		apiKey, _, err = issueApiKey(r.Context(), q, user.ID, "default", sql.NullTime{}, defaultScopes)
+		if err != nil {
+			return dbError(err, "Error Creating Api Key")
+		}
+
+		return nil
+	})
 	if err != nil {
-		respondWithError(w, 500, err.Error())
+		respondWithApiError(w, asApiError(err, "Error Creating User"))
 		return
 	}
 
-	user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	respondWithJSON(w, 200, res{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
 		Name:      name.Name,
+		Username:  user.Username.String,
+		ApiKey:    apiKey,
 	})
+}
 
-	if err != nil {
-		respondWithError(w, 500, "Error Creating User: "+err.Error())
-		return
+func (cfg *apiConfig) getUserHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	apiKey, _ := getApiKeyFromHeader(r)
+
+	type res struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+		Name      string    `json:"name"`
+		ApiKey    string    `json:"api_key"`
 	}
 
 	respondWithJSON(w, 200, res{
 		ID:        user.ID,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
-		Name:      name.Name,
-		ApiKey:    user.ApiKey,
+		Name:      user.Name,
+		ApiKey:    apiKey,
 	})
 }
 
-// TODO: Use the Auth Middleware for this ?? repeated code methinks
-func (cfg *apiConfig) getUserByApiKeyHandler(w http.ResponseWriter, r *http.Request) {
-	headerAuth := r.Header.Get("Authorization")
-	if headerAuth == "" {
-		respondWithError(w, 401, "Authorization header missing")
+func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name     *string `json:"name" validate:"notblank,max=200"`
+		Username *string `json:"username" validate:"max=64"`
+		Password *string `json:"password" validate:"min=8,maxbytes=72"`
+		// Needed to change the username or password once there is one
+		CurrentPassword *string `json:"current_password"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
 		return
 	}
 
-	apiKey := strings.TrimPrefix(headerAuth, "ApiKey ")
-	if apiKey == headerAuth {
-		respondWithError(w, 401, "Malformed Token")
+	// A username and password log in to a session with every scope the user
+	// has, so a key with fewer scopes mustn't be able to set them on its own
+	if b.Username != nil || b.Password != nil {
+		if user.PasswordHash.Valid {
+			if b.CurrentPassword == nil {
+				respondWithApiError(w, &apiError{
+					Status:  400,
+					Code:    codeValidation,
+					Message: "Request body failed validation",
+					Fields:  []fieldError{{Field: "current_password", Message: "is required to change the username or password"}},
+				})
+				return
+			}
+			err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(*b.CurrentPassword))
+			if err != nil {
+				respondWithApiError(w, errForbidden("Current password is incorrect"))
+				return
+			}
+		} else if auth := authFromContext(r.Context()); auth.ApiKey != nil {
+			for _, scope := range sessionScopes(user) {
+				if !hasScope(auth.Scopes, scope) {
+					respondWithApiError(w, errMissingScope(scope))
+					return
+				}
+			}
+		}
+	}
+
+	if b.Name != nil {
+		user.Name = *b.Name
+	}
+	if b.Username != nil {
+		user.Username = sql.NullString{String: *b.Username, Valid: *b.Username != ""}
+	}
+	if b.Password != nil {
+		var err error
+		user.PasswordHash, err = hashPassword(*b.Password)
+		if err != nil {
+			respondWithError(w, 500, "Error Hashing Password: "+err.Error())
+			return
+		}
+	}
+
+	// A password without a username can never be used to log in
+	if user.PasswordHash.Valid && !user.Username.Valid {
+		respondWithError(w, 400, "Username missing")
 		return
 	}
 
-	user, err := cfg.DB.GetUserByApiKey(r.Context(), apiKey)
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		user, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+			Name:         user.Name,
+			Username:     user.Username,
+			PasswordHash: user.PasswordHash,
+			UpdatedAt:    time.Now(),
+			ID:           user.ID,
+		})
+		if err != nil {
+			return dbError(err, "Error Updating User")
+		}
+
+		// A new password logs out every session the old one started
+		if b.Password != nil {
+			err = q.DeleteRefreshTokensByUser(r.Context(), user.ID)
+			if err != nil {
+				return dbError(err, "Error Revoking Sessions")
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		respondWithError(w, 500, "Error getting user by ApiKey: "+err.Error())
+		respondWithApiError(w, asApiError(err, "Error Updating User"))
 		return
 	}
 
@@ -91,7 +222,7 @@ func (cfg *apiConfig) getUserByApiKeyHandler(w http.ResponseWriter, r *http.Requ
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 		Name      string    `json:"name"`
-		ApiKey    string    `json:"api_key"`
+		Username  string    `json:"username,omitempty"`
 	}
 
 	respondWithJSON(w, 200, res{
@@ -99,65 +230,84 @@ func (cfg *apiConfig) getUserByApiKeyHandler(w http.ResponseWriter, r *http.Requ
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Name:      user.Name,
-		ApiKey:    apiKey,
+		Username:  user.Username.String,
 	})
 }
 
-func (cfg *apiConfig) createFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
-	type body struct {
-		Name string `json:"name"`
-		Url  string `json:"url"`
-	}
-
-	var b body
-	req, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(req, &b)
-
+// Deletes the account and everything hanging off it. Feeds the user created
+// are handed to another follower who can read them first so they don't vanish
+// for everyone else; feeds nobody else can read go with the user.
+func (cfg *apiConfig) deleteUserHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.ReassignFeedsFromUser(r.Context(), database.ReassignFeedsFromUserParams{
+			UserID:    user.ID,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			return dbError(err, "Error Reassigning Feeds")
+		}
+
+		err = q.DeleteUser(r.Context(), user.ID)
+		if err != nil {
+			return dbError(err, "Error Deleting User")
+		}
+
+		return nil
+	})
 	if err != nil {
-		respondWithError(w, 500, err.Error())
+		respondWithApiError(w, asApiError(err, "Error Deleting User"))
 		return
 	}
 
-	feed, err := cfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      b.Name,
-		Url:       b.Url,
-		UserID:    user.ID,
-	})
+	w.WriteHeader(200)
+}
 
-	if err != nil {
-		respondWithError(w, 500, "Error Creating Feed: "+err.Error())
+func (cfg *apiConfig) createFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name string `json:"name" validate:"required,max=200"`
+		Url  string `json:"url" validate:"required,max=2048,url"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
 		return
 	}
 
-	feed_follow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		UserID:    user.ID,
-		FeedID:    feed.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	var feed database.Feed
+	var feed_follow database.FeedFollow
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		feed, err = findOrCreateFeed(r.Context(), q, user.ID, b.Name, b.Url)
+		if err != nil {
+			return dbError(err, "Error Creating Feed")
+		}
+
+		feed_follow, err = followFeed(r.Context(), q, user.ID, feed.ID)
+		if err != nil {
+			return dbError(err, "Error Creating Feed Follow")
+		}
+
+		return nil
 	})
-
 	if err != nil {
-		respondWithError(w, 500, "Error Creating Feed Follow: "+err.Error())
+		respondWithApiError(w, asApiError(err, "Error Creating Feed"))
 		return
 	}
 
 	type res struct {
-		Feed       Feed                `json:"feed"`
-		FeedFollow database.FeedFollow `json:"feed_follow"`
+		Feed       Feed       `json:"feed"`
+		FeedFollow FeedFollow `json:"feed_follow"`
 	}
 
-	respondWithJSON(w, 200, res{Feed: databaseFeedToFeed(feed), FeedFollow: feed_follow})
+	respondWithJSON(w, 200, res{Feed: databaseFeedToFeed(feed), FeedFollow: databaseFeedFollowToFeedFollow(feed_follow, feed.Name)})
 }
 
 func (cfg *apiConfig) getAllFeedsHandler(w http.ResponseWriter, r *http.Request) {
 	dbFeeds, err := cfg.DB.GetAllFeeds(r.Context())
 
 	if err != nil {
-		respondWithError(w, 500, "Error Getting Feeds: "+err.Error())
+		respondWithApiError(w, dbError(err, "Error Getting Feeds"))
 		return
 	}
 
@@ -169,52 +319,243 @@ func (cfg *apiConfig) getAllFeedsHandler(w http.ResponseWriter, r *http.Request)
 	respondWithJSON(w, 200, feeds)
 }
 
-func (cfg *apiConfig) createFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+// Looks up the feed in the path and checks that the user created it. Admins
+// can manage any feed.
+func (cfg *apiConfig) getOwnedFeed(w http.ResponseWriter, r *http.Request, user database.User) (database.Feed, bool) {
+	feed, ok := cfg.getFeedFromPath(w, r)
+	if !ok {
+		return database.Feed{}, false
+	}
+
+	if feed.UserID != user.ID && !user.IsAdmin {
+		respondWithApiError(w, errForbidden("This user does not own the given feed"))
+		return database.Feed{}, false
+	}
+
+	return feed, true
+}
+
+func (cfg *apiConfig) updateFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getOwnedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
 	type body struct {
-		FeedId uuid.UUID `json:"feed_id"`
+		Name *string `json:"name" validate:"notblank,max=200"`
+		Url  *string `json:"url" validate:"max=2048,url"`
 	}
 
 	var b body
-	req, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(req, &b)
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	oldUrl := feed.Url
+	if b.Name != nil {
+		feed.Name = *b.Name
+	}
+	if b.Url != nil {
+		feed.Url = *b.Url
+	}
 
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		feed, err = q.UpdateFeed(r.Context(), database.UpdateFeedParams{
+			Name:         feed.Name,
+			Url:          feed.Url,
+			CanonicalUrl: canonicalURL(feed.Url),
+			UpdatedAt:    time.Now(),
+			ID:           feed.ID,
+		})
+		if err != nil {
+			return dbError(err, "Error Updating Feed")
+		}
+
+		if sameHost(oldUrl, feed.Url) {
+			return nil
+		}
+		return clearFeedCredentials(r.Context(), q, feed.ID)
+	})
 	if err != nil {
-		respondWithError(w, 500, err.Error())
+		respondWithApiError(w, asApiError(err, "Error Updating Feed"))
 		return
 	}
 
-	feedFollow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		UserID:    user.ID,
-		FeedID:    b.FeedId,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	respondWithJSON(w, 200, databaseFeedToFeed(feed))
+}
+
+// Feeds other people still follow are only deleted with ?transfer=true, which
+// hands the feed to its longest-standing other follower and drops the owner's
+// own follow instead. Only followers who can read the feed count, so a private
+// feed never goes to someone who didn't get through with their own
+// credentials. Admins wanting it gone for everyone use the admin API.
+func (cfg *apiConfig) deleteFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := cfg.getOwnedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	transfer := r.URL.Query().Get("transfer") == "true"
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		others, err := q.CountOtherFeedFollowers(r.Context(), database.CountOtherFeedFollowersParams{
+			FeedID: feed.ID,
+			UserID: feed.UserID,
+		})
+		if err != nil {
+			return dbError(err, "Error Counting Feed Followers")
+		}
+
+		if others == 0 {
+			err = q.DeleteFeed(r.Context(), feed.ID)
+			if err != nil {
+				return dbError(err, "Error Deleting Feed")
+			}
+			return nil
+		}
+
+		if !transfer {
+			return &apiError{
+				Status:  409,
+				Code:    codeConflict,
+				Message: fmt.Sprintf("Feed is followed by %d other users, pass ?transfer=true to hand it over instead", others),
+			}
+		}
+
+		_, err = q.TransferFeedOwnership(r.Context(), database.TransferFeedOwnershipParams{
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		})
+		if err != nil {
+			return dbError(err, "Error Transferring Feed")
+		}
+
+		// The old owner may have unfollowed already
+		_, err = q.DeleteFeedFollowByFeedId(r.Context(), database.DeleteFeedFollowByFeedIdParams{
+			UserID: feed.UserID,
+			FeedID: feed.ID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return dbError(err, "Error Deleting Feed Follow")
+		}
+
+		return nil
 	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Deleting Feed"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) createFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		FeedId uuid.UUID `json:"feed_id" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
 
+	var feed database.Feed
+	var feedFollow database.FeedFollow
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		feedFollow, err = followFeed(r.Context(), q, user.ID, b.FeedId)
+		if err != nil {
+			return dbError(err, "Error Creating Feed Follow")
+		}
+
+		feed, err = q.GetFeedById(r.Context(), feedFollow.FeedID)
+		if err != nil {
+			return dbError(err, "Error Getting Feed")
+		}
+
+		return nil
+	})
 	if err != nil {
-		respondWithError(w, 500, "Error Creating Feed Follow: "+err.Error())
+		respondWithApiError(w, asApiError(err, "Error Creating Feed Follow"))
 		return
 	}
 
-	respondWithJSON(w, 200, feedFollow)
+	// Following a feed twice is fine, you just get the follow you already had
+	respondWithJSON(w, 200, databaseFeedFollowToFeedFollow(feedFollow, feed.Name))
+}
+
+// Returns the feed at the URL, creating it under the user's name if we don't
+// have it yet. A feed we already have is found under any spelling of its URL,
+// or an old URL it moved away from, instead of being created again.
+func findOrCreateFeed(ctx context.Context, q *database.Queries, userID uuid.UUID, name, feedURL string) (database.Feed, error) {
+	canonical := canonicalURL(feedURL)
+	feed, err := q.GetFeedByCanonicalUrl(ctx, canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = q.GetFeedByAlias(ctx, canonical)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = q.CreateFeed(ctx, database.CreateFeedParams{
+			ID:           uuid.New(),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			Name:         name,
+			Url:          feedURL,
+			UserID:       userID,
+			CanonicalUrl: canonical,
+		})
+	}
+	return feed, err
+}
+
+// Returns the user's follow of the feed, creating it if they don't have one yet
+func followFeed(ctx context.Context, q *database.Queries, userID, feedID uuid.UUID) (database.FeedFollow, error) {
+	feedFollow, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	// No row means they already follow it
+	if errors.Is(err, sql.ErrNoRows) {
+		return q.GetFeedFollowByUserAndFeed(ctx, database.GetFeedFollowByUserAndFeedParams{
+			UserID: userID,
+			FeedID: feedID,
+		})
+	}
+	return feedFollow, err
 }
 
 func (cfg *apiConfig) deleteFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
-	id, err := uuid.Parse(r.PathValue("feedFollowID"))
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteFeedFollow(r.Context(), feedFollow.ID)
 	if err != nil {
-		respondWithError(w, 500, "Error getting feed follow ID: "+err.Error())
+		respondWithApiError(w, dbError(err, "Error Deleting Feed Follow"))
 		return
 	}
 
-	feed_follow, err := cfg.DB.GetFeedFollowById(r.Context(), id)
-	if feed_follow.UserID != user.ID {
-		respondWithError(w, 401, "This user does not own the given feed follow")
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) unfollowFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting feed ID: "+err.Error())
 		return
 	}
 
-	err = cfg.DB.DeleteFeedFollow(r.Context(), id)
+	_, err = cfg.DB.DeleteFeedFollowByFeedId(r.Context(), database.DeleteFeedFollowByFeedIdParams{
+		UserID: user.ID,
+		FeedID: feedID,
+	})
 	if err != nil {
-		respondWithError(w, 500, "Error Deleting Feed Follow: "+err.Error())
+		respondWithApiError(w, dbError(err, "Error Deleting Feed Follow"))
 		return
 	}
 
@@ -224,23 +565,143 @@ func (cfg *apiConfig) deleteFeedFollowHandler(w http.ResponseWriter, r *http.Req
 func (cfg *apiConfig) getFeedFollowsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	feed_follows, err := cfg.DB.GetFeedFollowsByUserId(r.Context(), user.ID)
 	if err != nil {
-		respondWithError(w, 500, "Error Getting Feed Follows: "+err.Error())
+		respondWithApiError(w, dbError(err, "Error Getting Feed Follows"))
+		return
+	}
+
+	follows := []any{}
+	for _, row := range feed_follows {
+		follows = append(follows, databaseFeedFollowToFeedFollow(database.FeedFollow{
+			ID:                 row.ID,
+			UserID:             row.UserID,
+			FeedID:             row.FeedID,
+			CreatedAt:          row.CreatedAt,
+			UpdatedAt:          row.UpdatedAt,
+			CustomTitle:        row.CustomTitle,
+			HiddenFromTimeline: row.HiddenFromTimeline,
+			Pinned:             row.Pinned,
+		}, row.FeedName))
+	}
+
+	// Saved searches act as virtual feeds, listed after the real follows.
+	// They're opt-in so clients that only know feed follows keep working.
+	if r.URL.Query().Get("saved_searches") == "true" {
+		searches, err := cfg.getSavedSearches(r.Context(), user.ID)
+		if err != nil {
+			respondWithApiError(w, dbError(err, "Error Getting Saved Searches"))
+			return
+		}
+		for _, search := range searches {
+			follows = append(follows, SavedSearchFollow{SavedSearch: search, Kind: "saved_search"})
+		}
+	}
+
+	respondWithJSON(w, 200, follows)
+}
+
+func (cfg *apiConfig) updateFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedFollow, ok := cfg.getOwnedFeedFollow(w, r, user)
+	if !ok {
+		return
+	}
+
+	type body struct {
+		Title              *string `json:"title" validate:"max=200"`
+		HiddenFromTimeline *bool   `json:"hidden_from_timeline"`
+		Pinned             *bool   `json:"pinned"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	var feed database.Feed
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		// Locked so a concurrent update's fields aren't written back over
+		var err error
+		feedFollow, err = q.GetFeedFollowByIdForUpdate(r.Context(), feedFollow.ID)
+		if err != nil {
+			return dbError(err, "Error Getting Feed Follow")
+		}
+
+		// An empty title goes back to using the feed's own name
+		if b.Title != nil {
+			feedFollow.CustomTitle = sql.NullString{String: *b.Title, Valid: *b.Title != ""}
+		}
+		if b.HiddenFromTimeline != nil {
+			feedFollow.HiddenFromTimeline = *b.HiddenFromTimeline
+		}
+		if b.Pinned != nil {
+			feedFollow.Pinned = *b.Pinned
+		}
+
+		feedFollow, err = q.UpdateFeedFollowSettings(r.Context(), database.UpdateFeedFollowSettingsParams{
+			CustomTitle:        feedFollow.CustomTitle,
+			HiddenFromTimeline: feedFollow.HiddenFromTimeline,
+			Pinned:             feedFollow.Pinned,
+			UpdatedAt:          time.Now(),
+			ID:                 feedFollow.ID,
+		})
+		if err != nil {
+			return dbError(err, "Error Updating Feed Follow")
+		}
+
+		feed, err = q.GetFeedById(r.Context(), feedFollow.FeedID)
+		if err != nil {
+			return dbError(err, "Error Getting Feed")
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Updating Feed Follow"))
 		return
 	}
-	respondWithJSON(w, 200, feed_follows)
+
+	respondWithJSON(w, 200, databaseFeedFollowToFeedFollow(feedFollow, feed.Name))
 }
 
 func (cfg *apiConfig) getPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
 
+	rules, err := cfg.compiledRulesFor(r.Context(), user.ID)
 	if err != nil {
-		limit = 10
+		respondWithApiError(w, dbError(err, "Error Getting Rules"))
+		return
+	}
+
+	filters := []postFilter{hideFilter(rules)}
+
+	// One post per story, with the copies from other feeds listed under it
+	var collapser *clusterCollapser
+	if r.URL.Query().Get("collapse") == "true" {
+		collapser = cfg.newClusterCollapser(r.Context())
+		filters = append(filters, collapser.filter)
 	}
 
-	posts, err := cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{UserID: user.ID, Limit: int32(limit)})
+	posts, err := pagePosts(limit, offset, func(limit, offset int32) ([]database.Post, error) {
+		return cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{UserID: user.ID, Limit: limit, Offset: offset})
+	}, filters...)
 	if err != nil {
-		respondWithError(w, 500, "Error Getting Posts: "+err.Error())
+		respondWithApiError(w, dbError(err, "Error Getting Posts"))
 		return
 	}
+
+	if collapser != nil {
+		collapsed, err := collapser.collapse(user.ID, rules, posts)
+		if err != nil {
+			respondWithApiError(w, dbError(err, "Error Collapsing Posts"))
+			return
+		}
+		respondWithJSON(w, 200, collapsed)
+		return
+	}
+
 	respondWithJSON(w, 200, posts)
 }
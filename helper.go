@@ -2,10 +2,11 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"sync"
@@ -15,17 +16,26 @@ import (
 	"github.com/saubuny/bootdev-rss/internal/database"
 )
 
+// Plain errors get the default code for their status
 func respondWithError(w http.ResponseWriter, code int, msg string) {
-	if code >= 500 {
-		fmt.Printf("Responding with 5XX error: %s", msg)
+	respondWithApiError(w, &apiError{Status: code, Code: codeForStatus(code), Message: msg})
+}
+
+func respondWithApiError(w http.ResponseWriter, err *apiError) {
+	if err.Status >= 500 {
+		fmt.Printf("Responding with 5XX error: %s", err.Message)
 	}
 
 	type errorResponse struct {
-		Error string `json:"error"`
+		Error  string       `json:"error"`
+		Code   string       `json:"code"`
+		Fields []fieldError `json:"fields,omitempty"`
 	}
 
-	respondWithJSON(w, code, errorResponse{
-		Error: msg,
+	respondWithJSON(w, err.Status, errorResponse{
+		Error:  err.Message,
+		Code:   err.Code,
+		Fields: err.Fields,
 	})
 }
 
@@ -61,6 +71,38 @@ func databaseFeedToFeed(feed database.Feed) Feed {
 	}
 }
 
+type FeedFollow struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	FeedID             uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Title              string
+	CustomTitle        string
+	HiddenFromTimeline bool
+	Pinned             bool
+}
+
+// Title falls back to the shared feed name unless the user renamed the feed for themselves
+func databaseFeedFollowToFeedFollow(feedFollow database.FeedFollow, feedName string) FeedFollow {
+	title := feedName
+	if feedFollow.CustomTitle.Valid {
+		title = feedFollow.CustomTitle.String
+	}
+
+	return FeedFollow{
+		ID:                 feedFollow.ID,
+		UserID:             feedFollow.UserID,
+		FeedID:             feedFollow.FeedID,
+		CreatedAt:          feedFollow.CreatedAt,
+		UpdatedAt:          feedFollow.UpdatedAt,
+		Title:              title,
+		CustomTitle:        feedFollow.CustomTitle.String,
+		HiddenFromTimeline: feedFollow.HiddenFromTimeline,
+		Pinned:             feedFollow.Pinned,
+	}
+}
+
 // Rss was generated 2024-09-04 09:22:05 by https://xml-to-go.github.io/ in Ukraine.
 type Rss struct {
 	XMLName xml.Name `xml:"rss"`
@@ -91,77 +133,223 @@ type Rss struct {
 	} `xml:"channel"`
 }
 
-func fetchFromFeed(feedUrl string) (Rss, error) {
-	resp, err := http.Get(feedUrl)
+// What the fetcher has been up to, for the admin API
+type fetcherStatus struct {
+	mu                sync.Mutex
+	LastRunStartedAt  time.Time
+	LastRunFinishedAt time.Time
+	FeedsFetched      int
+	PostsCreated      int
+	// Latest error per feed, cleared once the feed fetches cleanly again
+	Errors map[uuid.UUID]string
+}
+
+func (s *fetcherStatus) recordFetch(feedID uuid.UUID, posts int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.FeedsFetched++
+	s.PostsCreated += posts
+	if err != nil {
+		s.Errors[feedID] = err.Error()
+	} else {
+		delete(s.Errors, feedID)
+	}
+}
+
+const (
+	// A feed that failed waits this long before it's tried again, doubling with
+	// every failure in a row up to maxFetchBackoff
+	minFetchBackoff = 5 * time.Minute
+	maxFetchBackoff = 24 * time.Hour
+)
+
+func fetchBackoff(failures int32) time.Duration {
+	d := minFetchBackoff
+	for i := int32(1); i < failures && d < maxFetchBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxFetchBackoff)
+}
+
+// Keeps a failing feed out of the queue for a while, so it doesn't sit at the
+// front of every round ahead of feeds that work
+func (cfg *apiConfig) recordFetchFailure(ctx context.Context, feedID uuid.UUID, fetchErr error) {
+	// Shutting down isn't the feed's fault
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	failure, err := cfg.DB.RecordFeedFetchFailure(ctx, database.RecordFeedFetchFailureParams{
+		FeedID:    feedID,
+		LastError: fetchErr.Error(),
+		FailedAt:  now,
+	})
 	if err != nil {
-		return Rss{}, err
+		log.Println("Error recording fetch failure: " + err.Error())
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return Rss{}, fmt.Errorf("Status error: %v", resp.StatusCode)
+	next := now.Add(fetchBackoff(failure.Failures))
+	var backoff *hostBackoffError
+	if errors.As(fetchErr, &backoff) && backoff.until.After(next) {
+		next = backoff.until
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	err = cfg.DB.SetFeedNextAttempt(ctx, database.SetFeedNextAttemptParams{
+		NextAttemptAt: next,
+		FeedID:        feedID,
+	})
+	if err != nil {
+		log.Println("Error recording fetch failure: " + err.Error())
+	}
+}
+
+// Fetches a single feed and stores any posts we haven't seen before
+func (cfg *apiConfig) processFeed(ctx context.Context, feed database.Feed) {
+	created := 0
+	var rss Rss
+	var movedTo string
+	opts, err := cfg.fetchOptionsForFeed(ctx, feed.ID)
+	if err == nil {
+		rss, movedTo, err = cfg.FeedClient.fetch(ctx, feed.Url, opts)
+	}
+	defer func() {
+		cfg.Fetcher.recordFetch(feed.ID, created, err)
+		if err != nil {
+			cfg.recordFetchFailure(ctx, feed.ID, err)
+		}
+	}()
+
 	if err != nil {
-		return Rss{}, fmt.Errorf("Read body: %v", err)
+		log.Println("Error in feed fetch worker: " + err.Error())
+		return
 	}
 
-	var res Rss
-	err = xml.Unmarshal(data, &res)
+	feed, err = cfg.trackFeedRedirect(ctx, feed, movedTo)
+	if err != nil {
+		log.Println("Error tracking feed redirect: " + err.Error())
+		return
+	}
 
+	err = cfg.DB.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt:     time.Now(),
+		ID:            feed.ID,
+	})
 	if err != nil {
-		return res, err
+		log.Println("Error marking feed fetched: " + err.Error())
+		return
+	}
+
+	if err := cfg.DB.ClearFeedFetchFailures(ctx, feed.ID); err != nil {
+		log.Println("Error clearing fetch failures: " + err.Error())
+	}
+
+	for _, post := range rss.Channel.Item {
+		log.Println("Processing post: " + post.Title)
+		pubDate, err := time.Parse(time.RFC1123Z, post.PubDate)
+		if err != nil {
+			log.Println("Error in parsing pubDate: " + err.Error())
+			return
+		}
+		dbPost, err := cfg.DB.CreatePost(ctx, database.CreatePostParams{
+			ID:           uuid.New(),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			Title:        post.Title,
+			Url:          post.Link,
+			Description:  post.Description,
+			PublishedAt:  pubDate,
+			FeedID:       feed.ID,
+			CanonicalUrl: canonicalURL(post.Link),
+		})
+		// Already seen this post on an earlier fetch
+		if err != nil {
+			continue
+		}
+		created++
+
+		err = cfg.DB.MatchPostAgainstSavedSearches(ctx, database.MatchPostAgainstSavedSearchesParams{
+			MatchedAt: time.Now(),
+			PostID:    dbPost.ID,
+		})
+		if err != nil {
+			log.Println("Error matching saved searches: " + err.Error())
+		}
+		cfg.applyRulesToNewPost(ctx, dbPost)
+		cfg.clusterNewPost(ctx, dbPost)
 	}
+	log.Println("Feed processed: " + rss.Channel.Title)
+}
 
-	return res, nil
+// Fetches the feed in the background, counted among the fetches shutdown
+// waits for
+func (cfg *apiConfig) startFetch(feed database.Feed) {
+	cfg.Fetches.Add(1)
+	go func() {
+		defer cfg.Fetches.Done()
+		cfg.processFeed(cfg.FetchCtx, feed)
+	}()
 }
 
-func (cfg *apiConfig) feedFetchWorker() {
+// Fetches feeds in rounds until ctx is cancelled. Cancelling only stops new
+// fetches from starting; the ones in flight run under cfg.FetchCtx.
+func (cfg *apiConfig) feedFetchWorker(ctx context.Context) {
 	for {
 		log.Println("Fetching feeds from DB...")
-		feeds, err := cfg.DB.GetNextFeedsToFetch(context.Background(), 10)
-
-		if err != nil {
+		feeds, err := cfg.DB.GetNextFeedsToFetch(ctx, database.GetNextFeedsToFetchParams{Now: time.Now(), Limit: 10})
+		if err != nil && ctx.Err() == nil {
 			log.Println("Error in feed fetch worker: " + err.Error())
-			continue
 		}
 
-		log.Println("Processing feeds...")
-
-		var wg sync.WaitGroup
-		for _, feed := range feeds {
-			wg.Add(1)
-			go func(url string) {
-				defer wg.Done()
-				rss, err := fetchFromFeed(url)
-				if err != nil {
-					log.Println("Error in feed fetch worker: " + err.Error())
-					return
-				}
-				for _, post := range rss.Channel.Item {
-					log.Println("Processing post: " + post.Title)
-					pubDate, err := time.Parse(time.RFC1123Z, post.PubDate)
-					if err != nil {
-						log.Println("Error in parsing pubDate: " + err.Error())
-						return
-					}
-					cfg.DB.CreatePost(context.Background(), database.CreatePostParams{
-						ID:          uuid.New(),
-						CreatedAt:   time.Now(),
-						UpdatedAt:   time.Now(),
-						Title:       post.Title,
-						Url:         post.Link,
-						Description: post.Description,
-						PublishedAt: pubDate,
-						FeedID:      feed.ID,
-					})
-				}
-				log.Println("Feed processed: " + rss.Channel.Title)
-			}(feed.Url)
+		if err == nil {
+			cfg.fetchRound(ctx, feeds)
 		}
 
-		wg.Wait()
-		time.Sleep(60 * time.Second)
+		select {
+		case <-ctx.Done():
+			log.Println("Feed fetch worker stopped")
+			return
+		case <-time.After(60 * time.Second):
+		}
 	}
 }
+
+func (cfg *apiConfig) fetchRound(ctx context.Context, feeds []database.Feed) {
+	log.Println("Processing feeds...")
+
+	cfg.Fetcher.mu.Lock()
+	cfg.Fetcher.LastRunStartedAt = time.Now()
+	cfg.Fetcher.mu.Unlock()
+
+	// A fixed pool of workers, the feed client spaces out requests to the
+	// same host
+	jobs := make(chan database.Feed)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.FeedClient.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				cfg.processFeed(cfg.FetchCtx, feed)
+			}
+		}()
+	}
+
+send:
+	for _, feed := range feeds {
+		select {
+		case jobs <- feed:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	cfg.Fetcher.mu.Lock()
+	cfg.Fetcher.LastRunFinishedAt = time.Now()
+	cfg.Fetcher.mu.Unlock()
+}
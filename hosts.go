@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Waiting longer than this for a host fails the fetch instead of tying up
+	// a worker, the feed is tried again next round
+	maxHostWait = time.Minute
+	// Ignore anything past this in Retry-After, in case a server sends nonsense
+	maxRetryAfter = 6 * time.Hour
+)
+
+// Keeps us from hammering any one server: at most perHost requests in flight
+// to a host, at least delay between starting them, and none at all while the
+// host has asked us to back off with Retry-After
+type hostLimiter struct {
+	mu        sync.Mutex
+	perHost   int
+	delay     time.Duration
+	hosts     map[string]*hostState
+	lastSweep time.Time
+}
+
+type hostState struct {
+	active int
+	// Earliest the next request may start
+	next time.Time
+	// Closed and replaced whenever a request finishes
+	released chan struct{}
+}
+
+// A host told us not to come back until a given time
+type hostBackoffError struct {
+	msg   string
+	until time.Time
+}
+
+func (e *hostBackoffError) Error() string {
+	return e.msg
+}
+
+func newHostLimiter(perHost int, delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		perHost:   perHost,
+		delay:     delay,
+		hosts:     map[string]*hostState{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Forgets hosts with nothing in flight and nothing to wait for, they behave
+// the same as new ones. Nobody can be waiting on them either: with no
+// requests active and the delay passed, a waiter would have got in.
+func (l *hostLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for host, h := range l.hosts {
+		if h.active == 0 && !h.next.After(now) {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{released: make(chan struct{})}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// Blocks until a request to host may start. Every successful acquire must be
+// followed by a release.
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.sweep(now)
+		h := l.state(host)
+		wait := h.next.Sub(now)
+		if wait <= 0 && h.active < l.perHost {
+			h.active++
+			h.next = now.Add(l.delay)
+			l.mu.Unlock()
+			return nil
+		}
+		if wait > maxHostWait {
+			l.mu.Unlock()
+			return &hostBackoffError{
+				msg:   fmt.Sprintf("%s asked us to back off until %s", host, h.next.Format(time.RFC3339)),
+				until: h.next,
+			}
+		}
+		released := h.released
+		l.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-released:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.state(host)
+	h.active--
+	close(h.released)
+	h.released = make(chan struct{})
+}
+
+// Holds off all requests to host until the given time
+func (l *hostLimiter) backOff(host string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.state(host)
+	if until.After(h.next) {
+		h.next = until
+	}
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = at.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if d < 0 {
+		return 0, false
+	}
+	return min(d, maxRetryAfter), true
+}
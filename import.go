@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Most feeds one OPML import will follow
+const maxImportFeeds = 500
+
+type importedFeed struct {
+	Name   string `json:"name" validate:"required,max=200"`
+	Url    string `json:"url" validate:"required,max=2048,url"`
+	Folder string `json:"folder" validate:"max=100"`
+}
+
+// Flattens the outline tree into feeds. Outlines without an xmlUrl are
+// folders, and a feed goes in the nearest one around it.
+func collectOpmlFeeds(outlines []opmlOutline, folder string, feeds []importedFeed) []importedFeed {
+	for _, outline := range outlines {
+		name := outline.Title
+		if name == "" {
+			name = outline.Text
+		}
+
+		if outline.XmlUrl == "" {
+			feeds = collectOpmlFeeds(outline.Outlines, name, feeds)
+			continue
+		}
+
+		if name == "" {
+			name = outline.XmlUrl
+		}
+		feeds = append(feeds, importedFeed{Name: name, Url: outline.XmlUrl, Folder: folder})
+	}
+	return feeds
+}
+
+// Follows every feed in an OPML document, the same way exportUserHandler
+// writes them. Folders are matched by name and created when missing. Feeds
+// that don't validate are skipped and reported rather than failing the import.
+func (cfg *apiConfig) importFeedFollowsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		respondWithApiError(w, errInvalidBody(err))
+		return
+	}
+
+	var doc opml
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		respondWithApiError(w, errInvalidBody(err))
+		return
+	}
+
+	feeds := collectOpmlFeeds(doc.Body, "", nil)
+	if len(feeds) > maxImportFeeds {
+		respondWithApiError(w, &apiError{
+			Status:  400,
+			Code:    codeValidation,
+			Message: fmt.Sprintf("Too many feeds: at most %d can be imported at once", maxImportFeeds),
+		})
+		return
+	}
+
+	type skipped struct {
+		Url    string       `json:"url"`
+		Fields []fieldError `json:"fields"`
+	}
+
+	feedFollows := []FeedFollow{}
+	skippedFeeds := []skipped{}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		folders, err := q.GetFoldersByUserId(r.Context(), user.ID)
+		if err != nil {
+			return dbError(err, "Error Getting Folders")
+		}
+
+		folderIDs := map[string]uuid.UUID{}
+		for _, folder := range folders {
+			if _, ok := folderIDs[folder.Name]; !ok {
+				folderIDs[folder.Name] = folder.ID
+			}
+		}
+
+		seen := map[uuid.UUID]bool{}
+		for _, entry := range feeds {
+			if fields := validateStruct(entry); len(fields) > 0 {
+				skippedFeeds = append(skippedFeeds, skipped{Url: entry.Url, Fields: fields})
+				continue
+			}
+
+			feed, err := findOrCreateFeed(r.Context(), q, user.ID, entry.Name, entry.Url)
+			if err != nil {
+				return dbError(err, "Error Creating Feed")
+			}
+
+			feedFollow, err := followFeed(r.Context(), q, user.ID, feed.ID)
+			if err != nil {
+				return dbError(err, "Error Creating Feed Follow")
+			}
+
+			if entry.Folder != "" {
+				folderID, ok := folderIDs[entry.Folder]
+				if !ok {
+					now := time.Now()
+					folder, err := q.CreateFolder(r.Context(), database.CreateFolderParams{
+						ID:         uuid.New(),
+						UserID:     user.ID,
+						CreatedAt:  now,
+						UpdatedAt:  now,
+						Name:       entry.Folder,
+						LastReadAt: now,
+					})
+					if err != nil {
+						return dbError(err, "Error Creating Folder")
+					}
+					folderID = folder.ID
+					folderIDs[entry.Folder] = folderID
+				}
+
+				err = q.AddFeedFollowToFolder(r.Context(), database.AddFeedFollowToFolderParams{
+					FolderID:     folderID,
+					FeedFollowID: feedFollow.ID,
+					CreatedAt:    time.Now(),
+				})
+				if err != nil {
+					return dbError(err, "Error Adding Feed Follow To Folder")
+				}
+			}
+
+			// The same feed can be listed under several folders
+			if !seen[feedFollow.ID] {
+				seen[feedFollow.ID] = true
+				feedFollows = append(feedFollows, databaseFeedFollowToFeedFollow(feedFollow, feed.Name))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Importing Feeds"))
+		return
+	}
+
+	type res struct {
+		FeedFollows []FeedFollow `json:"feed_follows"`
+		Skipped     []skipped    `json:"skipped"`
+	}
+
+	respondWithJSON(w, 200, res{FeedFollows: feedFollows, Skipped: skippedFeeds})
+}
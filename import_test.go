@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestCollectOpmlFeeds(t *testing.T) {
+	tests := []struct {
+		name string
+		opml string
+		want []importedFeed
+	}{
+		{
+			"top level",
+			`<opml><body><outline text="Blog" xmlUrl="https://example.com/feed"/></body></opml>`,
+			[]importedFeed{{Name: "Blog", Url: "https://example.com/feed"}},
+		},
+		{
+			"title wins over text",
+			`<opml><body><outline text="blog" title="Blog" xmlUrl="https://example.com/feed"/></body></opml>`,
+			[]importedFeed{{Name: "Blog", Url: "https://example.com/feed"}},
+		},
+		{
+			"no name",
+			`<opml><body><outline xmlUrl="https://example.com/feed"/></body></opml>`,
+			[]importedFeed{{Name: "https://example.com/feed", Url: "https://example.com/feed"}},
+		},
+		{
+			"folder",
+			`<opml><body><outline text="Tech"><outline text="Blog" xmlUrl="https://example.com/feed"/></outline></body></opml>`,
+			[]importedFeed{{Name: "Blog", Url: "https://example.com/feed", Folder: "Tech"}},
+		},
+		{
+			"nearest folder",
+			`<opml><body><outline text="Tech"><outline text="Go"><outline text="Blog" xmlUrl="https://example.com/feed"/></outline></outline></body></opml>`,
+			[]importedFeed{{Name: "Blog", Url: "https://example.com/feed", Folder: "Go"}},
+		},
+		{
+			"empty folder",
+			`<opml><body><outline text="Tech"/></body></opml>`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc opml
+			if err := xml.Unmarshal([]byte(tt.opml), &doc); err != nil {
+				t.Fatal(err)
+			}
+			got := collectOpmlFeeds(doc.Body, "", nil)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectOpmlFeeds() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
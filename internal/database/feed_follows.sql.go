@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,8 @@ import (
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, feed_id, created_at, updated_at
+ON CONFLICT (user_id, feed_id) DO NOTHING
+RETURNING id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned
 `
 
 type CreateFeedFollowParams struct {
@@ -41,6 +43,9 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.FeedID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
 	)
 	return i, err
 }
@@ -54,8 +59,34 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteFeedFollowByFeedId = `-- name: DeleteFeedFollowByFeedId :one
+DELETE FROM feed_follows WHERE user_id = $1 AND feed_id = $2
+RETURNING id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned
+`
+
+type DeleteFeedFollowByFeedIdParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollowByFeedId(ctx context.Context, arg DeleteFeedFollowByFeedIdParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, deleteFeedFollowByFeedId, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
+	)
+	return i, err
+}
+
 const getFeedFollowById = `-- name: GetFeedFollowById :one
-SELECT id, user_id, feed_id, created_at, updated_at FROM feed_follows WHERE id = $1
+SELECT id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned FROM feed_follows WHERE id = $1
 `
 
 func (q *Queries) GetFeedFollowById(ctx context.Context, id uuid.UUID) (FeedFollow, error) {
@@ -67,29 +98,100 @@ func (q *Queries) GetFeedFollowById(ctx context.Context, id uuid.UUID) (FeedFoll
 		&i.FeedID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
+	)
+	return i, err
+}
+
+const getFeedFollowByIdForUpdate = `-- name: GetFeedFollowByIdForUpdate :one
+SELECT id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned FROM feed_follows WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetFeedFollowByIdForUpdate(ctx context.Context, id uuid.UUID) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowByIdForUpdate, id)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
+	)
+	return i, err
+}
+
+const getFeedFollowByUserAndFeed = `-- name: GetFeedFollowByUserAndFeed :one
+SELECT id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned FROM feed_follows WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowByUserAndFeedParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollowByUserAndFeed(ctx context.Context, arg GetFeedFollowByUserAndFeedParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowByUserAndFeed, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
 	)
 	return i, err
 }
 
 const getFeedFollowsByUserId = `-- name: GetFeedFollowsByUserId :many
-SELECT id, user_id, feed_id, created_at, updated_at FROM feed_follows WHERE user_id = $1
+SELECT feed_follows.id, feed_follows.user_id, feed_follows.feed_id, feed_follows.created_at, feed_follows.updated_at, feed_follows.custom_title, feed_follows.hidden_from_timeline, feed_follows.pinned, feeds.name AS feed_name, feeds.url AS feed_url FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.pinned DESC, feed_follows.created_at
 `
 
-func (q *Queries) GetFeedFollowsByUserId(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
+type GetFeedFollowsByUserIdRow struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	FeedID             uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	CustomTitle        sql.NullString
+	HiddenFromTimeline bool
+	Pinned             bool
+	FeedName           string
+	FeedUrl            string
+}
+
+func (q *Queries) GetFeedFollowsByUserId(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsByUserIdRow, error) {
 	rows, err := q.db.QueryContext(ctx, getFeedFollowsByUserId, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []FeedFollow
+	var items []GetFeedFollowsByUserIdRow
 	for rows.Next() {
-		var i FeedFollow
+		var i GetFeedFollowsByUserIdRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.FeedID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CustomTitle,
+			&i.HiddenFromTimeline,
+			&i.Pinned,
+			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -103,3 +205,40 @@ func (q *Queries) GetFeedFollowsByUserId(ctx context.Context, userID uuid.UUID)
 	}
 	return items, nil
 }
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET custom_title = $1, hidden_from_timeline = $2, pinned = $3, updated_at = $4
+WHERE id = $5
+RETURNING id, user_id, feed_id, created_at, updated_at, custom_title, hidden_from_timeline, pinned
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	CustomTitle        sql.NullString
+	HiddenFromTimeline bool
+	Pinned             bool
+	UpdatedAt          time.Time
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.CustomTitle,
+		arg.HiddenFromTimeline,
+		arg.Pinned,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CustomTitle,
+		&i.HiddenFromTimeline,
+		&i.Pinned,
+	)
+	return i, err
+}
@@ -13,19 +13,38 @@ import (
 	"github.com/google/uuid"
 )
 
+const countOtherFeedFollowers = `-- name: CountOtherFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+AND can_read_feed(user_id, feed_id)
+`
+
+type CountOtherFeedFollowersParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CountOtherFeedFollowers(ctx context.Context, arg CountOtherFeedFollowersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherFeedFollowers, arg.FeedID, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, user_id, created_at, updated_at, name, url)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at
+INSERT INTO feeds (id, user_id, created_at, updated_at, name, url, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	Url          string
+	CanonicalUrl string
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -36,6 +55,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.UpdatedAt,
 		arg.Name,
 		arg.Url,
+		arg.CanonicalUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -46,12 +66,23 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Name,
 		&i.Url,
 		&i.LastFetchedAt,
+		&i.DisabledAt,
+		&i.CanonicalUrl,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getAllFeeds = `-- name: GetAllFeeds :many
-SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at FROM feeds
+SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url FROM feeds
 `
 
 func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
@@ -71,6 +102,8 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Name,
 			&i.Url,
 			&i.LastFetchedAt,
+			&i.DisabledAt,
+			&i.CanonicalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -85,14 +118,65 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
+const getFeedByCanonicalUrl = `-- name: GetFeedByCanonicalUrl :one
+SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url FROM feeds WHERE canonical_url = $1
+`
+
+func (q *Queries) GetFeedByCanonicalUrl(ctx context.Context, canonicalUrl string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByCanonicalUrl, canonicalUrl)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.LastFetchedAt,
+		&i.DisabledAt,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
+
+const getFeedById = `-- name: GetFeedById :one
+SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedById(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedById, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.LastFetchedAt,
+		&i.DisabledAt,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
+
 const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at FROM feeds
-ORDER BY last_fetched_at
-LIMIT $1
+SELECT feeds.id, feeds.user_id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.last_fetched_at, feeds.disabled_at, feeds.canonical_url FROM feeds
+LEFT JOIN feed_fetch_failures
+ON feed_fetch_failures.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (feed_fetch_failures.next_attempt_at IS NULL OR feed_fetch_failures.next_attempt_at <= $1)
+ORDER BY feeds.last_fetched_at NULLS FIRST
+LIMIT $2
 `
 
-func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+type GetNextFeedsToFetchParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.Now, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +192,8 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 			&i.Name,
 			&i.Url,
 			&i.LastFetchedAt,
+			&i.DisabledAt,
+			&i.CanonicalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -138,3 +224,123 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
 	return err
 }
+
+const reassignFeedsFromUser = `-- name: ReassignFeedsFromUser :exec
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = $2
+WHERE feeds.user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+)
+`
+
+type ReassignFeedsFromUserParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ReassignFeedsFromUser(ctx context.Context, arg ReassignFeedsFromUserParams) error {
+	_, err := q.db.ExecContext(ctx, reassignFeedsFromUser, arg.UserID, arg.UpdatedAt)
+	return err
+}
+
+const setFeedDisabled = `-- name: SetFeedDisabled :exec
+UPDATE feeds
+SET disabled_at = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetFeedDisabledParams struct {
+	DisabledAt sql.NullTime
+	UpdatedAt  time.Time
+	ID         uuid.UUID
+}
+
+func (q *Queries) SetFeedDisabled(ctx context.Context, arg SetFeedDisabledParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedDisabled, arg.DisabledAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const transferFeedOwnership = `-- name: TransferFeedOwnership :one
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> feeds.user_id
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = $1
+WHERE id = $2
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url
+`
+
+type TransferFeedOwnershipParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) TransferFeedOwnership(ctx context.Context, arg TransferFeedOwnershipParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, transferFeedOwnership, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.LastFetchedAt,
+		&i.DisabledAt,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
+
+const updateFeed = `-- name: UpdateFeed :one
+UPDATE feeds
+SET name = $1, url = $2, canonical_url = $3, updated_at = $4
+WHERE id = $5
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, disabled_at, canonical_url
+`
+
+type UpdateFeedParams struct {
+	Name         string
+	Url          string
+	CanonicalUrl string
+	UpdatedAt    time.Time
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeed,
+		arg.Name,
+		arg.Url,
+		arg.CanonicalUrl,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.LastFetchedAt,
+		&i.DisabledAt,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
@@ -6,11 +6,24 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	Name       string
+	Prefix     string
+	KeyHash    string
+	ExpiresAt  sql.NullTime
+	Scopes     []string
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	UserID        uuid.UUID
@@ -19,31 +32,150 @@ type Feed struct {
 	Name          string
 	Url           string
 	LastFetchedAt sql.NullTime
+	DisabledAt    sql.NullTime
+	CanonicalUrl  string
+}
+
+type FeedFetchFailure struct {
+	FeedID        uuid.UUID
+	Failures      int32
+	LastError     string
+	FailedAt      time.Time
+	NextAttemptAt time.Time
+}
+
+type FeedFetchSetting struct {
+	FeedID          uuid.UUID
+	Headers         json.RawMessage
+	UpdatedAt       time.Time
+	CredentialsKind sql.NullString
+	Credentials     []byte
 }
 
 type FeedFollow struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	FeedID             uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	CustomTitle        sql.NullString
+	HiddenFromTimeline bool
+	Pinned             bool
+}
+
+type FeedFollowCredential struct {
+	FeedFollowID uuid.UUID
+	Kind         string
+	Encrypted    []byte
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type FeedRedirect struct {
+	FeedID      uuid.UUID
+	Url         string
+	SeenCount   int32
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+type FeedUrlAlias struct {
+	Url       string
 	FeedID    uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type Folder struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Name       string
+	LastReadAt time.Time
+}
+
+type FolderFeedFollow struct {
+	FolderID     uuid.UUID
+	FeedFollowID uuid.UUID
+	CreatedAt    time.Time
 }
 
 type Post struct {
-	ID          uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  string
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	CanonicalUrl string
+}
+
+type PostFingerprint struct {
+	PostID      uuid.UUID
+	Fingerprint int64
+	ClusterID   uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
 }
 
-type User struct {
+type PostFingerprintBand struct {
+	PostID  uuid.UUID
+	BandKey int32
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	TokenHash string
+}
+
+type Rule struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
-	ApiKey    string
+	Field     string
+	Pattern   string
+	Action    string
+	HitCount  int32
+}
+
+type SavedSearch struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	LastReadAt time.Time
+	Name       string
+	Query      string
+	TsQuery    string
+}
+
+type SavedSearchPost struct {
+	SavedSearchID uuid.UUID
+	PostID        uuid.UUID
+	CreatedAt     time.Time
+}
+
+type StarredPost struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	Username     sql.NullString
+	PasswordHash sql.NullString
+	IsAdmin      bool
+	DisabledAt   sql.NullTime
 }
@@ -13,20 +13,21 @@ import (
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, canonical_url
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  string
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	CanonicalUrl string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -39,6 +40,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.CanonicalUrl,
 	)
 	var i Post
 	err := row.Scan(
@@ -50,27 +52,30 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.CanonicalUrl,
 	)
 	return i, err
 }
 
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts
-INNER JOIN feeds
-ON posts.feed_id = feeds.id
-INNER JOIN users
-ON feeds.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+SELECT DISTINCT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.canonical_url FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT feed_follows.hidden_from_timeline
+AND can_read_feed(feed_follows.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3
 `
 
 type GetPostsByUserParams struct {
 	UserID uuid.UUID
 	Limit  int32
+	Offset int32
 }
 
 func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +92,7 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.CanonicalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -100,3 +106,165 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const getStarredPostsByUser = `-- name: GetStarredPostsByUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.canonical_url FROM posts
+INNER JOIN starred_posts
+ON starred_posts.post_id = posts.id
+WHERE starred_posts.user_id = $1
+AND can_read_feed(starred_posts.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3
+`
+
+type GetStarredPostsByUserParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) GetStarredPostsByUser(ctx context.Context, arg GetStarredPostsByUserParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsByUser, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.CanonicalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPostsByUser = `-- name: SearchPostsByUser :many
+SELECT matches.id, matches.created_at, matches.updated_at, matches.title, matches.url, matches.description, matches.published_at, matches.feed_id, matches.canonical_url, matches.rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(matches.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    ) AS title_snippet,
+    ts_headline(
+        'english',
+        replace(replace(regexp_replace(matches.description, '<[^>]*>', ' ', 'g'), '<', '&lt;'), '>', '&gt;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+    ) AS description_snippet
+FROM (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.canonical_url,
+        ts_rank_cd(
+            setweight(to_tsvector('english', posts.title), 'A') ||
+            setweight(to_tsvector('english', posts.description), 'B'),
+            query
+        )::real AS rank
+    FROM posts
+    INNER JOIN feed_follows
+    ON posts.feed_id = feed_follows.feed_id
+    CROSS JOIN to_tsquery('english', $1) query
+    WHERE feed_follows.user_id = $2
+    AND can_read_feed(feed_follows.user_id, posts.feed_id)
+    AND (
+        setweight(to_tsvector('english', posts.title), 'A') ||
+        setweight(to_tsvector('english', posts.description), 'B')
+    ) @@ query
+    ORDER BY rank DESC, posts.published_at DESC, posts.id
+    LIMIT $3 OFFSET $4
+) matches
+CROSS JOIN to_tsquery('english', $1) query
+ORDER BY matches.rank DESC, matches.published_at DESC, matches.id
+`
+
+type SearchPostsByUserParams struct {
+	Query  string
+	UserID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+type SearchPostsByUserRow struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Title              string
+	Url                string
+	Description        string
+	PublishedAt        time.Time
+	FeedID             uuid.UUID
+	CanonicalUrl       string
+	Rank               float32
+	TitleSnippet       string
+	DescriptionSnippet string
+}
+
+func (q *Queries) SearchPostsByUser(ctx context.Context, arg SearchPostsByUserParams) ([]SearchPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsByUser, arg.Query, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsByUserRow
+	for rows.Next() {
+		var i SearchPostsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.CanonicalUrl,
+			&i.Rank,
+			&i.TitleSnippet,
+			&i.DescriptionSnippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO starred_posts (user_id, post_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type StarPostParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID, arg.CreatedAt)
+	return err
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 
 	_ "github.com/lib/pq"
@@ -13,7 +22,18 @@ import (
 )
 
 type apiConfig struct {
-	DB *database.Queries
+	DB             *database.Queries
+	Conn           *sql.DB
+	JWTSecret      []byte
+	CredentialsKey []byte
+	Fetcher        *fetcherStatus
+	FeedClient     *feedClient
+	// Per client IP, checked before authenticating
+	AuthLimit *rateLimiter
+	// Fetches run under FetchCtx, which is only cancelled if they haven't
+	// drained by the end of the shutdown timeout
+	FetchCtx context.Context
+	Fetches  *sync.WaitGroup
 }
 
 func main() {
@@ -23,26 +43,192 @@ func main() {
 	db, err := sql.Open("postgres", dbUrl)
 	dbQueries := database.New(db)
 
-	cfg := apiConfig{DB: dbQueries}
+	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+	if len(jwtSecret) == 0 {
+		log.Println("[Warn] JWT_SECRET not set, sessions will not survive a restart")
+		jwtSecret = make([]byte, 32)
+		rand.Read(jwtSecret)
+	}
+
+	credentialsKey, err := credentialsKeyFromEnv()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if credentialsKey == nil {
+		log.Println("[Warn] CREDENTIALS_KEY not set, private feeds are disabled")
+	}
+
+	fetchConfig, err := fetcherConfigFromEnv()
+	if err != nil {
+		log.Fatal("Invalid fetcher config: " + err.Error())
+	}
+	feedClient, err := newFeedClient(fetchConfig)
+	if err != nil {
+		log.Fatal("Error creating feed client: " + err.Error())
+	}
+
+	// How long in-flight requests and fetches get to finish on SIGINT/SIGTERM
+	shutdownTimeout := 30 * time.Second
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		shutdownTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("Invalid SHUTDOWN_TIMEOUT: " + err.Error())
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fetchCtx, cancelFetches := context.WithCancel(context.Background())
+	defer cancelFetches()
+
+	cfg := apiConfig{
+		DB:             dbQueries,
+		Conn:           db,
+		JWTSecret:      jwtSecret,
+		CredentialsKey: credentialsKey,
+		Fetcher:        &fetcherStatus{Errors: map[uuid.UUID]string{}},
+		FeedClient:     feedClient,
+		AuthLimit:      newRateLimiter(20, 100),
+		FetchCtx:       fetchCtx,
+		Fetches:        &sync.WaitGroup{},
+	}
+
+	// One-off maintenance, run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill-canonical-urls":
+			err = cfg.backfillCanonicalURLs(ctx)
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
+	// Requests per second and burst size, per user or client IP
+	signupLimit := newRateLimiter(1.0/60, 5)
+	loginLimit := newRateLimiter(1.0/10, 10)
+	publicLimit := newRateLimiter(5, 30)
+	readLimit := newRateLimiter(5, 30)
+	writeLimit := newRateLimiter(1, 20)
+	timelineLimit := newRateLimiter(1, 10)
+	exportLimit := newRateLimiter(1.0/3600, 2)
+	importLimit := newRateLimiter(1.0/60, 5)
 
 	serveMux := http.NewServeMux()
 	serveMux.HandleFunc("GET /v1/healthz", healthHandler)
 	serveMux.HandleFunc("GET /v1/err", errorHandler)
-	serveMux.HandleFunc("POST /v1/users", cfg.createUserHandler)
-	serveMux.HandleFunc("GET /v1/users", cfg.getUserByApiKeyHandler)
-	serveMux.HandleFunc("POST /v1/feeds", cfg.middlewareAuth(cfg.createFeedHandler))
-	serveMux.HandleFunc("GET /v1/feeds", cfg.getAllFeedsHandler)
-	serveMux.HandleFunc("POST /v1/feed_follows", cfg.middlewareAuth(cfg.createFeedFollowHandler))
-	serveMux.HandleFunc("GET /v1/feed_follows", cfg.middlewareAuth(cfg.getFeedFollowsHandler))
-	serveMux.HandleFunc("DELETE /v1/feed_follows/{feedFollowID}", cfg.middlewareAuth((cfg.deleteFeedFollowHandler)))
-	serveMux.HandleFunc("GET /v1/posts", cfg.middlewareAuth((cfg.getPostsHandler)))
-
-	go cfg.feedFetchWorker()
-	server := http.Server{Handler: serveMux, Addr: "localhost:" + port}
-	fmt.Println("[Info] Starting server on port", 8080)
-	err = server.ListenAndServe()
+	serveMux.HandleFunc("POST /v1/users", signupLimit.byIP(cfg.createUserHandler))
+	serveMux.HandleFunc("GET /v1/users", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getUserHandler)))
+	serveMux.HandleFunc("PATCH /v1/users", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.updateUserHandler)))
+	serveMux.HandleFunc("DELETE /v1/users", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteUserHandler)))
+	serveMux.HandleFunc("GET /v1/users/export", cfg.middlewareAuth(scopeRead, exportLimit.authed(cfg.exportUserHandler)))
+	serveMux.HandleFunc("POST /v1/login", loginLimit.byIP(cfg.loginHandler))
+	serveMux.HandleFunc("POST /v1/refresh", loginLimit.byIP(cfg.refreshHandler))
+	serveMux.HandleFunc("POST /v1/logout", loginLimit.byIP(cfg.logoutHandler))
+	serveMux.HandleFunc("POST /v1/users/api_key/rotate", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.rotateApiKeyHandler)))
+	serveMux.HandleFunc("POST /v1/api_keys", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createApiKeyHandler)))
+	serveMux.HandleFunc("GET /v1/api_keys", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getApiKeysHandler)))
+	serveMux.HandleFunc("DELETE /v1/api_keys/{apiKeyID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteApiKeyHandler)))
+	serveMux.HandleFunc("POST /v1/feeds", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createFeedHandler)))
+	serveMux.HandleFunc("GET /v1/feeds", publicLimit.byIP(cfg.getAllFeedsHandler))
+	serveMux.HandleFunc("PATCH /v1/feeds/{feedID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.updateFeedHandler)))
+	serveMux.HandleFunc("DELETE /v1/feeds/{feedID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteFeedHandler)))
+	serveMux.HandleFunc("GET /v1/feeds/{feedID}/fetch_settings", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getFeedFetchSettingsHandler)))
+	serveMux.HandleFunc("PUT /v1/feeds/{feedID}/fetch_settings", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.putFeedFetchSettingsHandler)))
+	serveMux.HandleFunc("DELETE /v1/feeds/{feedID}/fetch_settings", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteFeedFetchSettingsHandler)))
+	serveMux.HandleFunc("DELETE /v1/feeds/{feedID}/follow", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.unfollowFeedHandler)))
+	serveMux.HandleFunc("POST /v1/feed_follows", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createFeedFollowHandler)))
+	serveMux.HandleFunc("GET /v1/feed_follows", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getFeedFollowsHandler)))
+	serveMux.HandleFunc("POST /v1/feed_follows/import", cfg.middlewareAuth(scopeWrite, importLimit.authed(cfg.importFeedFollowsHandler)))
+	serveMux.HandleFunc("DELETE /v1/feed_follows/{feedFollowID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteFeedFollowHandler)))
+	serveMux.HandleFunc("PATCH /v1/feed_follows/{feedFollowID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.updateFeedFollowHandler)))
+	serveMux.HandleFunc("PUT /v1/feed_follows/{feedFollowID}/credentials", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.putFeedFollowCredentialsHandler)))
+	serveMux.HandleFunc("DELETE /v1/feed_follows/{feedFollowID}/credentials", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteFeedFollowCredentialsHandler)))
+	serveMux.HandleFunc("GET /v1/posts", cfg.middlewareAuth(scopeRead, timelineLimit.authed(cfg.getPostsHandler)))
+	serveMux.HandleFunc("GET /v1/search", cfg.middlewareAuth(scopeRead, timelineLimit.authed(cfg.searchPostsHandler)))
+	serveMux.HandleFunc("POST /v1/saved_searches", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createSavedSearchHandler)))
+	serveMux.HandleFunc("GET /v1/saved_searches", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getSavedSearchesHandler)))
+	serveMux.HandleFunc("GET /v1/saved_searches/{savedSearchID}/posts", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getSavedSearchPostsHandler)))
+	serveMux.HandleFunc("POST /v1/saved_searches/{savedSearchID}/read", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.markSavedSearchReadHandler)))
+	serveMux.HandleFunc("DELETE /v1/saved_searches/{savedSearchID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteSavedSearchHandler)))
+	serveMux.HandleFunc("POST /v1/rules", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createRuleHandler)))
+	serveMux.HandleFunc("GET /v1/rules", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getRulesHandler)))
+	serveMux.HandleFunc("POST /v1/rules/dry_run", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.dryRunRuleHandler)))
+	serveMux.HandleFunc("PATCH /v1/rules/{ruleID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.updateRuleHandler)))
+	serveMux.HandleFunc("DELETE /v1/rules/{ruleID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteRuleHandler)))
+	serveMux.HandleFunc("GET /v1/posts/starred", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getStarredPostsHandler)))
+	serveMux.HandleFunc("POST /v1/folders", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.createFolderHandler)))
+	serveMux.HandleFunc("GET /v1/folders", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getFoldersHandler)))
+	serveMux.HandleFunc("PATCH /v1/folders/{folderID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.renameFolderHandler)))
+	serveMux.HandleFunc("DELETE /v1/folders/{folderID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.deleteFolderHandler)))
+	serveMux.HandleFunc("POST /v1/folders/{folderID}/read", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.markFolderReadHandler)))
+	serveMux.HandleFunc("GET /v1/folders/{folderID}/posts", cfg.middlewareAuth(scopeRead, readLimit.authed(cfg.getFolderPostsHandler)))
+	serveMux.HandleFunc("PUT /v1/folders/{folderID}/feed_follows/{feedFollowID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.addFeedFollowToFolderHandler)))
+	serveMux.HandleFunc("DELETE /v1/folders/{folderID}/feed_follows/{feedFollowID}", cfg.middlewareAuth(scopeWrite, writeLimit.authed(cfg.removeFeedFollowFromFolderHandler)))
+	serveMux.HandleFunc("GET /v1/admin/users", cfg.middlewareAdmin(cfg.adminGetUsersHandler))
+	serveMux.HandleFunc("POST /v1/admin/users/{userID}/disable", cfg.middlewareAdmin(cfg.adminSetUserDisabled(true)))
+	serveMux.HandleFunc("POST /v1/admin/users/{userID}/enable", cfg.middlewareAdmin(cfg.adminSetUserDisabled(false)))
+	serveMux.HandleFunc("POST /v1/admin/feeds/{feedID}/refresh", cfg.middlewareAdmin(cfg.adminRefreshFeedHandler))
+	serveMux.HandleFunc("POST /v1/admin/feeds/{feedID}/disable", cfg.middlewareAdmin(cfg.adminSetFeedDisabled(true)))
+	serveMux.HandleFunc("POST /v1/admin/feeds/{feedID}/enable", cfg.middlewareAdmin(cfg.adminSetFeedDisabled(false)))
+	serveMux.HandleFunc("DELETE /v1/admin/feeds/{feedID}", cfg.middlewareAdmin(cfg.adminDeleteFeedHandler))
+	serveMux.HandleFunc("GET /v1/admin/fetcher", cfg.middlewareAdmin(cfg.adminFetcherStatusHandler))
+
+	cfg.Fetches.Add(1)
+	go func() {
+		defer cfg.Fetches.Done()
+		cfg.feedFetchWorker(ctx)
+	}()
+
+	// e.g. X-Forwarded-For or X-Real-IP, whichever the reverse proxy in front
+	// of us sets
+	proxyHeader := os.Getenv("TRUSTED_PROXY_HEADER")
+	server := http.Server{Handler: trustProxyHeader(proxyHeader, serveMux), Addr: "localhost:" + port}
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Println("[Info] Starting server on port", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	case <-ctx.Done():
+	}
+	stop()
+
+	fmt.Println("[Info] Shutting down, waiting up to", shutdownTimeout, "for requests and fetches")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Requests first, so nothing can start another fetch while we wait
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
-		panic(err)
+		// Cut off requests still running past the timeout. Closing their
+		// connections cancels their contexts, and with them their queries,
+		// before the database goes away.
+		log.Println("Error shutting down server: " + err.Error())
+		server.Close()
 	}
+
+	drained := make(chan struct{})
+	go func() {
+		cfg.Fetches.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-shutdownCtx.Done():
+		log.Println("Fetches still running at shutdown timeout, cancelling them")
+		cancelFetches()
+		<-drained
+	}
+
+	db.Close()
 	fmt.Println("[Info] Server ended")
 }
@@ -1,33 +1,118 @@
 package main
 
 import (
-	"github.com/saubuny/bootdev-rss/internal/database"
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/saubuny/bootdev-rss/internal/database"
 )
 
 type authedHandler func(http.ResponseWriter, *http.Request, database.User)
 
-func (cfg *apiConfig) middlewareAuth(handler authedHandler) http.HandlerFunc {
+type contextKey string
+
+const authContextKey contextKey = "auth"
+
+// How a request was authenticated, set by middlewareAuth
+type authContext struct {
+	// Nil when the request came in with a session token
+	ApiKey *database.ApiKey
+	Scopes []string
+}
+
+func authFromContext(ctx context.Context) authContext {
+	auth, _ := ctx.Value(authContextKey).(authContext)
+	return auth
+}
+
+// Accepts either "ApiKey <key>" or "Bearer <access token>"
+func (cfg *apiConfig) middlewareAuth(scope string, handler authedHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		headerAuth := r.Header.Get("Authorization")
-		if headerAuth == "" {
-			respondWithError(w, 401, "Authorization header missing")
+		// Before any lookups, so guessing keys can't be used to hammer the database
+		if !cfg.AuthLimit.check(w, ipKey(r)) {
 			return
 		}
 
-		apiKey := strings.TrimPrefix(headerAuth, "ApiKey ")
-		if apiKey == headerAuth {
-			respondWithError(w, 401, "Malformed Token")
+		var auth authContext
+		var user database.User
+
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			userID, err := cfg.verifyAccessToken(token)
+			if err != nil {
+				respondWithApiError(w, &apiError{Status: 401, Code: codeInvalidToken, Message: err.Error()})
+				return
+			}
+
+			user, err = cfg.DB.GetUserById(r.Context(), userID)
+			// The account was deleted after the token was issued
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithApiError(w, &apiError{Status: 401, Code: codeInvalidToken, Message: "Invalid Token"})
+				return
+			}
+			if err != nil {
+				respondWithApiError(w, dbError(err, "Error getting user by token"))
+				return
+			}
+
+			auth = authContext{Scopes: sessionScopes(user)}
+		} else {
+			apiKey, msg := getApiKeyFromHeader(r)
+			if msg != "" {
+				respondWithError(w, 401, msg)
+				return
+			}
+
+			key, err := cfg.DB.GetApiKeyByHash(r.Context(), hashApiKey(apiKey))
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithApiError(w, &apiError{Status: 401, Code: codeInvalidApiKey, Message: "Invalid ApiKey"})
+				return
+			}
+			if err != nil {
+				respondWithApiError(w, dbError(err, "Error getting user by ApiKey"))
+				return
+			}
+
+			if key.ExpiresAt.Valid && time.Now().After(key.ExpiresAt.Time) {
+				respondWithApiError(w, &apiError{Status: 401, Code: codeApiKeyExpired, Message: "ApiKey expired"})
+				return
+			}
+
+			err = cfg.DB.TouchApiKey(r.Context(), database.TouchApiKeyParams{
+				LastUsedAt: sql.NullTime{Time: time.Now(), Valid: true},
+				ID:         key.ID,
+			})
+			if err != nil {
+				respondWithApiError(w, dbError(err, "Error updating ApiKey"))
+				return
+			}
+
+			user, err = cfg.DB.GetUserById(r.Context(), key.UserID)
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithApiError(w, &apiError{Status: 401, Code: codeInvalidApiKey, Message: "Invalid ApiKey"})
+				return
+			}
+			if err != nil {
+				respondWithApiError(w, dbError(err, "Error getting user by ApiKey"))
+				return
+			}
+
+			auth = authContext{ApiKey: &key, Scopes: key.Scopes}
+		}
+
+		if user.DisabledAt.Valid {
+			respondWithApiError(w, &apiError{Status: 403, Code: codeAccountDisabled, Message: "Account disabled"})
 			return
 		}
 
-		user, err := cfg.DB.GetUserByApiKey(r.Context(), apiKey)
-		if err != nil {
-			respondWithError(w, 500, "Error getting user by ApiKey: "+err.Error())
+		if !hasScope(auth.Scopes, scope) {
+			respondWithApiError(w, errMissingScope(scope))
 			return
 		}
 
-		handler(w, r, user)
+		handler(w, r.WithContext(context.WithValue(r.Context(), authContextKey, auth)), user)
 	}
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Token bucket limiter. Every caller gets a bucket of burst tokens that
+// refills at rate tokens per second, and each request takes one.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   map[string]*tokenBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Takes a token for key if there is one. Also reports how many are left and
+// how long until the next one is available.
+func (l *rateLimiter) allow(key string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	bucket.tokens--
+	return true, int(bucket.tokens), 0
+}
+
+// Forgets buckets that have had time to fill back up, they behave the same as new ones
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Checks the limit for key and sets the rate limit headers. Returns false
+// after answering with a 429.
+func (l *rateLimiter) check(w http.ResponseWriter, key string) bool {
+	allowed, remaining, wait := l.allow(key)
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(l.burst)))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	refill := time.Duration((l.burst - float64(remaining)) / l.rate * float64(time.Second))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(refill).Unix(), 10))
+
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondWithError(w, 429, "Rate limit exceeded")
+		return false
+	}
+
+	return true
+}
+
+// Limits an authenticated route per user. All of a user's keys and sessions
+// share one bucket, so minting more keys doesn't buy a higher limit.
+func (l *rateLimiter) authed(handler authedHandler) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if l.check(w, "user:"+user.ID.String()) {
+			handler(w, r, user)
+		}
+	}
+}
+
+// Limits an unauthenticated route per client IP
+func (l *rateLimiter) byIP(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.check(w, ipKey(r)) {
+			handler(w, r)
+		}
+	}
+}
+
+func ipKey(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+// Behind a reverse proxy every request comes from the proxy, so the client's
+// address is taken from the header the proxy sets instead. Only name a header
+// the proxy always overwrites, otherwise clients can pick their own address.
+func trustProxyHeader(header string, next http.Handler) http.Handler {
+	if header == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := clientIPFromHeader(r.Header.Get(header)); ip != "" {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Clients can send their own X-Forwarded-For, which the proxy appends the
+// address it saw to, so only the last entry is trusted
+func clientIPFromHeader(value string) string {
+	entries := strings.Split(value, ",")
+	ip := strings.TrimSpace(entries[len(entries)-1])
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// How many fetches in a row have to be permanently redirected to the same URL
+// before we move the feed there. One bad deploy on the publisher's side
+// shouldn't lose us the original URL.
+const redirectsBeforeMove = 3
+
+// Private feeds are never merged. Their posts are only readable by people who
+// got through with credentials, which the other feed's followers haven't.
+var errPrivateFeedMerge = errors.New("private feeds can't be merged")
+
+// Records where the feed redirected to on this fetch and moves it once the
+// redirect has been seen consistently. Returns the feed to carry on with,
+// which is a different row if the feed was merged into one we already had.
+func (cfg *apiConfig) trackFeedRedirect(ctx context.Context, feed database.Feed, movedTo string) (database.Feed, error) {
+	if movedTo == "" {
+		return feed, cfg.DB.ClearFeedRedirect(ctx, feed.ID)
+	}
+
+	redirect, err := cfg.DB.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		FeedID: feed.ID,
+		Url:    movedTo,
+		SeenAt: time.Now(),
+	})
+	if err != nil {
+		return feed, err
+	}
+
+	if redirect.SeenCount < redirectsBeforeMove {
+		return feed, nil
+	}
+
+	log.Println("Feed moved permanently: " + feed.Url + " -> " + movedTo)
+	return cfg.moveFeed(ctx, feed, movedTo)
+}
+
+// Points the feed at its new URL and keeps the old one as an alias. If another
+// feed already has the new URL, everything hanging off this feed is moved over
+// to that one and this feed is deleted, unless either of them is private.
+// Moving to another host drops the feed's credentials.
+func (cfg *apiConfig) moveFeed(ctx context.Context, feed database.Feed, newUrl string) (database.Feed, error) {
+	canonical := canonicalURL(newUrl)
+
+	var moved database.Feed
+	err := cfg.inTx(ctx, func(q *database.Queries) error {
+		existing, err := q.GetFeedByCanonicalUrl(ctx, canonical)
+		// An http -> https move, say, canonicalises to the feed we already are
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && existing.ID == feed.ID) {
+			moved, err = q.UpdateFeed(ctx, database.UpdateFeedParams{
+				Name:         feed.Name,
+				Url:          newUrl,
+				CanonicalUrl: canonical,
+				UpdatedAt:    time.Now(),
+				ID:           feed.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			// Credentials were given for the old host, not whoever it
+			// redirects to
+			if !sameHost(feed.Url, newUrl) {
+				err = clearFeedCredentials(ctx, q, feed.ID)
+				if err != nil {
+					return err
+				}
+			}
+		} else if err != nil {
+			return err
+		} else {
+			moved = existing
+			err = mergeFeed(ctx, q, feed.ID, existing.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = q.ClearFeedRedirect(ctx, feed.ID)
+		if err != nil {
+			return err
+		}
+
+		if feed.CanonicalUrl == canonical {
+			return nil
+		}
+		return q.CreateFeedUrlAlias(ctx, database.CreateFeedUrlAliasParams{
+			Url:       feed.CanonicalUrl,
+			FeedID:    moved.ID,
+			CreatedAt: time.Now(),
+		})
+	})
+	// The redirect stays recorded, and the feed keeps being fetched from the
+	// URL its credentials were given for
+	if errors.Is(err, errPrivateFeedMerge) {
+		log.Println("Not merging private feed " + feed.Url + " into " + newUrl)
+		return feed, nil
+	}
+	if err != nil {
+		return feed, err
+	}
+
+	return moved, nil
+}
+
+// Moves follows, posts, rules, saved searches, aliases and fetch settings from
+// one feed to another, then deletes the old feed. Users who already follow
+// both keep the follow they had on the surviving feed, with the folders,
+// custom title and credentials of the old one added where it has none.
+// Returns errPrivateFeedMerge without changing anything if either feed is
+// private.
+func mergeFeed(ctx context.Context, q *database.Queries, from, to uuid.UUID) error {
+	now := time.Now()
+
+	for _, id := range []uuid.UUID{from, to} {
+		private, err := q.IsFeedPrivate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if private {
+			return errPrivateFeedMerge
+		}
+	}
+
+	err := q.MergeFeedFollowFolders(ctx, database.MergeFeedFollowFoldersParams{ToFeedID: to, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MergeFeedFollowTitles(ctx, database.MergeFeedFollowTitlesParams{UpdatedAt: now, FromFeedID: from, ToFeedID: to})
+	if err != nil {
+		return err
+	}
+
+	err = q.MergeFeedFollowCredentials(ctx, database.MergeFeedFollowCredentialsParams{ToFeedID: to, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: to, UpdatedAt: now, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MoveFeedPosts(ctx, database.MoveFeedPostsParams{ToFeedID: to, UpdatedAt: now, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MoveFeedRules(ctx, database.MoveFeedRulesParams{ToFeedID: to, UpdatedAt: now, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MoveFeedSavedSearches(ctx, database.MoveFeedSavedSearchesParams{ToFeedID: to, UpdatedAt: now, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	err = q.MoveFeedUrlAliases(ctx, database.MoveFeedUrlAliasesParams{ToFeedID: to, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	// The surviving feed's own settings win
+	err = q.MoveFeedFetchSettings(ctx, database.MoveFeedFetchSettingsParams{ToFeedID: to, FromFeedID: from})
+	if err != nil {
+		return err
+	}
+
+	return q.DeleteFeed(ctx, from)
+}
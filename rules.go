@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+const (
+	ruleFieldTitle       = "title"
+	ruleFieldDescription = "description"
+	ruleFieldAny         = "any"
+
+	ruleActionHide = "hide"
+	ruleActionStar = "star"
+)
+
+// How many of the user's latest posts a dry run is checked against
+const ruleDryRunPosts = 100
+
+type Rule struct {
+	ID        uuid.UUID  `json:"id"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Name      string     `json:"name"`
+	Field     string     `json:"field"`
+	Pattern   string     `json:"pattern"`
+	Action    string     `json:"action"`
+	HitCount  int32      `json:"hit_count"`
+}
+
+func databaseRuleToRule(rule database.Rule) Rule {
+	var feedID *uuid.UUID
+	if rule.FeedID.Valid {
+		feedID = &rule.FeedID.UUID
+	}
+
+	return Rule{
+		ID:        rule.ID,
+		FeedID:    feedID,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+		Name:      rule.Name,
+		Field:     rule.Field,
+		Pattern:   rule.Pattern,
+		Action:    rule.Action,
+		HitCount:  rule.HitCount,
+	}
+}
+
+// Checks the parts of a rule that the database can't
+func validateRule(field, pattern, action string) string {
+	if field != ruleFieldTitle && field != ruleFieldDescription && field != ruleFieldAny {
+		return "Rule field must be one of title, description or any"
+	}
+	if action != ruleActionHide && action != ruleActionStar {
+		return "Rule action must be one of hide or star"
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "Invalid rule pattern: " + err.Error()
+	}
+	return ""
+}
+
+// A rule with its pattern compiled once, to be matched against many posts
+type compiledRule struct {
+	database.Rule
+	re *regexp.Regexp
+}
+
+// Patterns are validated on the way in, so rules only get dropped here for
+// rows edited by hand
+func compileRules(rules []database.Rule) []compiledRule {
+	compiled := []compiledRule{}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledRule{Rule: rule, re: re})
+	}
+	return compiled
+}
+
+func (rule compiledRule) matches(post database.Post) bool {
+	if rule.FeedID.Valid && rule.FeedID.UUID != post.FeedID {
+		return false
+	}
+
+	switch rule.Field {
+	case ruleFieldTitle:
+		return rule.re.MatchString(post.Title)
+	case ruleFieldDescription:
+		return rule.re.MatchString(post.Description)
+	default:
+		return rule.re.MatchString(post.Title) || rule.re.MatchString(post.Description)
+	}
+}
+
+func isHidden(rules []compiledRule, post database.Post) bool {
+	for _, rule := range rules {
+		if rule.Action == ruleActionHide && rule.matches(post) {
+			return true
+		}
+	}
+	return false
+}
+
+// Loads the user's rules ready for hideFilter
+func (cfg *apiConfig) compiledRulesFor(ctx context.Context, userID uuid.UUID) ([]compiledRule, error) {
+	rules, err := cfg.DB.GetRulesByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return compileRules(rules), nil
+}
+
+// Decides which of a batch of posts to keep. Filters may remember what they
+// have seen across batches.
+type postFilter func(posts []database.Post) ([]database.Post, error)
+
+// Drops every post caught by one of the user's hide rules
+func hideFilter(rules []compiledRule) postFilter {
+	return func(posts []database.Post) ([]database.Post, error) {
+		visible := []database.Post{}
+		for _, post := range posts {
+			if !isHidden(rules, post) {
+				visible = append(visible, post)
+			}
+		}
+		return visible, nil
+	}
+}
+
+// Most posts the filters may drop while filling one page. Past this the page
+// comes back short rather than reading through every post the user has.
+const maxFilteredPosts = 5000
+
+// Pages through posts that are filtered in Go. Posts are fetched in batches
+// until limit of them get through the filters, and offset counts filtered
+// posts, so pages come back full and don't skip anything.
+func pagePosts(limit, offset int32, fetch func(limit, offset int32) ([]database.Post, error), filters ...postFilter) ([]database.Post, error) {
+	batch := int32(min(max(int(limit)+int(offset), 50), 500))
+
+	page := []database.Post{}
+	skipped := int32(0)
+	dropped := 0
+	for from := int32(0); ; from += batch {
+		posts, err := fetch(batch, from)
+		if err != nil {
+			return nil, err
+		}
+		fetched := len(posts)
+
+		for _, filter := range filters {
+			posts, err = filter(posts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dropped += fetched - len(posts)
+
+		for _, post := range posts {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			page = append(page, post)
+			if len(page) == int(limit) {
+				return page, nil
+			}
+		}
+
+		if fetched < int(batch) || dropped >= maxFilteredPosts {
+			return page, nil
+		}
+	}
+}
+
+// Runs the rules of every follower against a freshly fetched post
+func (cfg *apiConfig) applyRulesToNewPost(ctx context.Context, post database.Post) {
+	rules, err := cfg.DB.GetRulesForFeed(ctx, post.FeedID)
+	if err != nil {
+		log.Println("Error getting rules for feed: " + err.Error())
+		return
+	}
+
+	for _, rule := range compileRules(rules) {
+		if !rule.matches(post) {
+			continue
+		}
+
+		err = cfg.DB.IncrementRuleHits(ctx, rule.ID)
+		if err != nil {
+			log.Println("Error counting rule hit: " + err.Error())
+		}
+
+		if rule.Action == ruleActionStar {
+			err = cfg.DB.StarPost(ctx, database.StarPostParams{
+				UserID:    rule.UserID,
+				PostID:    post.ID,
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				log.Println("Error starring post: " + err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *apiConfig) createRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name    string     `json:"name" validate:"max=100"`
+		FeedID  *uuid.UUID `json:"feed_id"`
+		Field   string     `json:"field" validate:"required"`
+		Pattern string     `json:"pattern" validate:"required,max=500"`
+		Action  string     `json:"action" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	if msg := validateRule(b.Field, b.Pattern, b.Action); msg != "" {
+		respondWithError(w, 400, msg)
+		return
+	}
+
+	feedID := uuid.NullUUID{}
+	if b.FeedID != nil {
+		feedID = uuid.NullUUID{UUID: *b.FeedID, Valid: true}
+	}
+
+	rule, err := cfg.DB.CreateRule(r.Context(), database.CreateRuleParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      b.Name,
+		Field:     b.Field,
+		Pattern:   b.Pattern,
+		Action:    b.Action,
+	})
+
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Creating Rule"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseRuleToRule(rule))
+}
+
+func (cfg *apiConfig) getRulesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	dbRules, err := cfg.DB.GetRulesByUserId(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Rules"))
+		return
+	}
+
+	rules := []Rule{}
+	for _, rule := range dbRules {
+		rules = append(rules, databaseRuleToRule(rule))
+	}
+
+	respondWithJSON(w, 200, rules)
+}
+
+// Looks up the rule in the path and checks that the user owns it
+func (cfg *apiConfig) getOwnedRule(w http.ResponseWriter, r *http.Request, user database.User) (database.Rule, bool) {
+	id, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting rule ID: "+err.Error())
+		return database.Rule{}, false
+	}
+
+	rule, err := cfg.DB.GetRuleById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Rule"))
+		return database.Rule{}, false
+	}
+
+	if rule.UserID != user.ID {
+		respondWithApiError(w, errForbidden("This user does not own the given rule"))
+		return database.Rule{}, false
+	}
+
+	return rule, true
+}
+
+func (cfg *apiConfig) updateRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	rule, ok := cfg.getOwnedRule(w, r, user)
+	if !ok {
+		return
+	}
+
+	type body struct {
+		Name *string `json:"name" validate:"max=100"`
+		// Raw so an explicit null, which widens the rule to every feed, can
+		// be told apart from leaving it out
+		FeedID  json.RawMessage `json:"feed_id"`
+		Field   *string         `json:"field" validate:"notblank"`
+		Pattern *string         `json:"pattern" validate:"notblank,max=500"`
+		Action  *string         `json:"action" validate:"notblank"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	if b.Name != nil {
+		rule.Name = *b.Name
+	}
+	if b.FeedID != nil {
+		// null unmarshals to the zero NullUUID
+		if err := json.Unmarshal(b.FeedID, &rule.FeedID); err != nil {
+			respondWithApiError(w, &apiError{
+				Status:  400,
+				Code:    codeValidation,
+				Message: "Request body failed validation",
+				Fields:  []fieldError{{Field: "feed_id", Message: "must be a feed ID or null"}},
+			})
+			return
+		}
+	}
+	if b.Field != nil {
+		rule.Field = *b.Field
+	}
+	if b.Pattern != nil {
+		rule.Pattern = *b.Pattern
+	}
+	if b.Action != nil {
+		rule.Action = *b.Action
+	}
+
+	if msg := validateRule(rule.Field, rule.Pattern, rule.Action); msg != "" {
+		respondWithError(w, 400, msg)
+		return
+	}
+
+	rule, err := cfg.DB.UpdateRule(r.Context(), database.UpdateRuleParams{
+		FeedID:    rule.FeedID,
+		UpdatedAt: time.Now(),
+		Name:      rule.Name,
+		Field:     rule.Field,
+		Pattern:   rule.Pattern,
+		Action:    rule.Action,
+		ID:        rule.ID,
+	})
+
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Updating Rule"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseRuleToRule(rule))
+}
+
+func (cfg *apiConfig) deleteRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	rule, ok := cfg.getOwnedRule(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteRule(r.Context(), rule.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Rule"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Shows which of the user's recent posts a rule would catch, without saving it
+func (cfg *apiConfig) dryRunRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		FeedID  *uuid.UUID `json:"feed_id"`
+		Field   string     `json:"field" validate:"required"`
+		Pattern string     `json:"pattern" validate:"required,max=500"`
+		Action  string     `json:"action" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	if msg := validateRule(b.Field, b.Pattern, b.Action); msg != "" {
+		respondWithError(w, 400, msg)
+		return
+	}
+
+	rule := compiledRule{
+		Rule: database.Rule{Field: b.Field, Pattern: b.Pattern, Action: b.Action},
+		re:   regexp.MustCompile(b.Pattern),
+	}
+	if b.FeedID != nil {
+		rule.FeedID = uuid.NullUUID{UUID: *b.FeedID, Valid: true}
+	}
+
+	posts, err := cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{UserID: user.ID, Limit: ruleDryRunPosts})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Posts"))
+		return
+	}
+
+	matches := []database.Post{}
+	for _, post := range posts {
+		if rule.matches(post) {
+			matches = append(matches, post)
+		}
+	}
+
+	respondWithJSON(w, 200, matches)
+}
+
+func (cfg *apiConfig) getStarredPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	posts, err := cfg.DB.GetStarredPostsByUser(r.Context(), database.GetStarredPostsByUserParams{UserID: user.ID, Limit: limit, Offset: offset})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Starred Posts"))
+		return
+	}
+	respondWithJSON(w, 200, posts)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+func TestPagePosts(t *testing.T) {
+	posts := make([]database.Post, 20000)
+	for i := range posts {
+		posts[i] = database.Post{ID: uuid.New(), Title: "post"}
+	}
+	// Every third post is hidden
+	for i := 0; i < len(posts); i += 3 {
+		posts[i].Title = "hidden"
+	}
+	rules := compileRules([]database.Rule{{Field: ruleFieldTitle, Pattern: "^hidden$", Action: ruleActionHide}})
+
+	tests := []struct {
+		name       string
+		posts      []database.Post
+		limit      int32
+		offset     int32
+		filter     bool
+		wantLen    int
+		wantFirst  int
+		maxScanned int
+	}{
+		{"unfiltered", posts[:100], 10, 0, false, 10, 0, 100},
+		{"unfiltered offset", posts[:100], 10, 5, false, 10, 5, 100},
+		{"short last page", posts[:25], 10, 20, false, 5, 20, 25},
+		{"filtered", posts[:100], 10, 0, true, 10, 1, 100},
+		{"filtered offset counts visible posts", posts[:100], 10, 2, true, 10, 4, 100},
+		{"filtered past the end", posts[:30], 10, 20, true, 0, 0, 30},
+		{"everything hidden stops early", hideAll(posts), 10, 0, true, 0, 0, maxFilteredPosts + 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanned := 0
+			fetch := func(limit, offset int32) ([]database.Post, error) {
+				from := min(int(offset), len(tt.posts))
+				to := min(from+int(limit), len(tt.posts))
+				scanned += to - from
+				return tt.posts[from:to], nil
+			}
+
+			var filters []postFilter
+			if tt.filter {
+				filters = append(filters, hideFilter(rules))
+			}
+
+			page, err := pagePosts(tt.limit, tt.offset, fetch, filters...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) != tt.wantLen {
+				t.Fatalf("got %d posts, want %d", len(page), tt.wantLen)
+			}
+			if len(page) > 0 && page[0].ID != tt.posts[tt.wantFirst].ID {
+				t.Errorf("page starts at the wrong post, want post %d", tt.wantFirst)
+			}
+			if scanned > tt.maxScanned {
+				t.Errorf("scanned %d posts, want at most %d", scanned, tt.maxScanned)
+			}
+		})
+	}
+}
+
+func hideAll(posts []database.Post) []database.Post {
+	hidden := make([]database.Post, len(posts))
+	for i, post := range posts {
+		post.Title = "hidden"
+		hidden[i] = post
+	}
+	return hidden
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+type SavedSearch struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      *uuid.UUID `json:"feed_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastReadAt  time.Time  `json:"last_read_at"`
+	Name        string     `json:"name"`
+	Query       string     `json:"query"`
+	UnreadCount int64      `json:"unread_count"`
+}
+
+// A saved search listed among the user's feed follows, marked so clients can
+// tell it apart from a real follow
+type SavedSearchFollow struct {
+	SavedSearch
+	Kind string `json:"kind"`
+}
+
+func databaseSavedSearchToSavedSearch(search database.SavedSearch, unreadCount int64) SavedSearch {
+	var feedID *uuid.UUID
+	if search.FeedID.Valid {
+		feedID = &search.FeedID.UUID
+	}
+
+	return SavedSearch{
+		ID:          search.ID,
+		FeedID:      feedID,
+		CreatedAt:   search.CreatedAt,
+		UpdatedAt:   search.UpdatedAt,
+		LastReadAt:  search.LastReadAt,
+		Name:        search.Name,
+		Query:       search.Query,
+		UnreadCount: unreadCount,
+	}
+}
+
+func (cfg *apiConfig) createSavedSearchHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type body struct {
+		Name   string     `json:"name" validate:"max=100"`
+		Query  string     `json:"query" validate:"required,max=500"`
+		FeedID *uuid.UUID `json:"feed_id"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	tsQuery := buildTsQuery(b.Query)
+	if tsQuery == "" {
+		respondWithError(w, 400, "Search query missing")
+		return
+	}
+
+	feedID := uuid.NullUUID{}
+	if b.FeedID != nil {
+		feedID = uuid.NullUUID{UUID: *b.FeedID, Valid: true}
+	}
+
+	now := time.Now()
+	var search database.SavedSearch
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		search, err = q.CreateSavedSearch(r.Context(), database.CreateSavedSearchParams{
+			ID:         uuid.New(),
+			UserID:     user.ID,
+			FeedID:     feedID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			LastReadAt: now,
+			Name:       b.Name,
+			Query:      b.Query,
+			TsQuery:    tsQuery,
+		})
+		if err != nil {
+			return dbError(err, "Error Creating Saved Search")
+		}
+
+		// Pick up everything already in the user's feeds, the fetcher keeps it
+		// current from here. Matches are stamped with the search's created_at,
+		// the same time as last_read_at, so they start out read.
+		err = q.MatchSavedSearchAgainstPosts(r.Context(), search.ID)
+		if err != nil {
+			return dbError(err, "Error Matching Saved Search")
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Creating Saved Search"))
+		return
+	}
+
+	respondWithJSON(w, 200, databaseSavedSearchToSavedSearch(search, 0))
+}
+
+// Loads the user's saved searches along with their unread counts
+func (cfg *apiConfig) getSavedSearches(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error) {
+	rows, err := cfg.DB.GetSavedSearchesByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	searches := []SavedSearch{}
+	for _, row := range rows {
+		searches = append(searches, databaseSavedSearchToSavedSearch(database.SavedSearch{
+			ID:         row.ID,
+			UserID:     row.UserID,
+			FeedID:     row.FeedID,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			LastReadAt: row.LastReadAt,
+			Name:       row.Name,
+			Query:      row.Query,
+			TsQuery:    row.TsQuery,
+		}, row.UnreadCount))
+	}
+	return searches, nil
+}
+
+func (cfg *apiConfig) getSavedSearchesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	searches, err := cfg.getSavedSearches(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Saved Searches"))
+		return
+	}
+
+	respondWithJSON(w, 200, searches)
+}
+
+// Looks up the saved search in the path and checks that the user owns it
+func (cfg *apiConfig) getOwnedSavedSearch(w http.ResponseWriter, r *http.Request, user database.User) (database.SavedSearch, bool) {
+	id, err := uuid.Parse(r.PathValue("savedSearchID"))
+	if err != nil {
+		respondWithError(w, 400, "Error getting saved search ID: "+err.Error())
+		return database.SavedSearch{}, false
+	}
+
+	search, err := cfg.DB.GetSavedSearchById(r.Context(), id)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Saved Search"))
+		return database.SavedSearch{}, false
+	}
+
+	if search.UserID != user.ID {
+		respondWithApiError(w, errForbidden("This user does not own the given saved search"))
+		return database.SavedSearch{}, false
+	}
+
+	return search, true
+}
+
+func (cfg *apiConfig) getSavedSearchPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	search, ok := cfg.getOwnedSavedSearch(w, r, user)
+	if !ok {
+		return
+	}
+
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	rules, err := cfg.compiledRulesFor(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Rules"))
+		return
+	}
+
+	posts, err := pagePosts(limit, offset, func(limit, offset int32) ([]database.Post, error) {
+		return cfg.DB.GetSavedSearchPosts(r.Context(), database.GetSavedSearchPostsParams{
+			SavedSearchID: search.ID,
+			Limit:         limit,
+			Offset:        offset,
+		})
+	}, hideFilter(rules))
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Posts"))
+		return
+	}
+
+	respondWithJSON(w, 200, posts)
+}
+
+func (cfg *apiConfig) markSavedSearchReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	search, ok := cfg.getOwnedSavedSearch(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.MarkSavedSearchRead(r.Context(), database.MarkSavedSearchReadParams{
+		LastReadAt: time.Now(),
+		ID:         search.ID,
+	})
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Marking Saved Search Read"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func (cfg *apiConfig) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	search, ok := cfg.getOwnedSavedSearch(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.DeleteSavedSearch(r.Context(), search.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Saved Search"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
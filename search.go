@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Turns a user search string into a to_tsquery expression. Quoted text becomes
+// a phrase query, a trailing * makes a prefix match and a leading - negates.
+func buildTsQuery(q string) string {
+	var terms []string
+	for i, part := range strings.Split(q, "\"") {
+		// Odd parts sit between a pair of quotes
+		if i%2 == 1 {
+			var words []string
+			for _, word := range strings.Fields(part) {
+				if word = sanitizeSearchWord(word); word != "" {
+					words = append(words, word)
+				}
+			}
+			if len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			negate := strings.HasPrefix(word, "-")
+			prefix := strings.HasSuffix(word, "*")
+			word = sanitizeSearchWord(word)
+			if word == "" {
+				continue
+			}
+			if prefix {
+				word += ":*"
+			}
+			if negate {
+				word = "!" + word
+			}
+			terms = append(terms, word)
+		}
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// Strips everything that has meaning in tsquery syntax
+func sanitizeSearchWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, word)
+}
+
+// Snippets are only built for the page being returned, ts_headline is slow.
+// Titles are escaped and descriptions stripped of their HTML before
+// highlighting, so <mark> is the only markup a snippet can contain.
+func (cfg *apiConfig) searchPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := buildTsQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, 400, "Search query missing")
+		return
+	}
+
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	rules, err := cfg.compiledRulesFor(r.Context(), user.ID)
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Getting Rules"))
+		return
+	}
+
+	// Rules filter posts, so the rank and snippets ride along by post ID
+	results := map[uuid.UUID]database.SearchPostsByUserRow{}
+	page, err := pagePosts(limit, offset, func(limit, offset int32) ([]database.Post, error) {
+		rows, err := cfg.DB.SearchPostsByUser(r.Context(), database.SearchPostsByUserParams{
+			Query:  query,
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		posts := []database.Post{}
+		for _, row := range rows {
+			results[row.ID] = row
+			posts = append(posts, database.Post{
+				ID:           row.ID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+				Title:        row.Title,
+				Url:          row.Url,
+				Description:  row.Description,
+				PublishedAt:  row.PublishedAt,
+				FeedID:       row.FeedID,
+				CanonicalUrl: row.CanonicalUrl,
+			})
+		}
+		return posts, err
+	}, hideFilter(rules))
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Searching Posts"))
+		return
+	}
+
+	type res struct {
+		ID                 uuid.UUID `json:"id"`
+		FeedID             uuid.UUID `json:"feed_id"`
+		Title              string    `json:"title"`
+		Url                string    `json:"url"`
+		PublishedAt        time.Time `json:"published_at"`
+		Rank               float32   `json:"rank"`
+		TitleSnippet       string    `json:"title_snippet"`
+		DescriptionSnippet string    `json:"description_snippet"`
+	}
+
+	posts := []res{}
+	for _, post := range page {
+		result := results[post.ID]
+		posts = append(posts, res{
+			ID:                 result.ID,
+			FeedID:             result.FeedID,
+			Title:              result.Title,
+			Url:                result.Url,
+			PublishedAt:        result.PublishedAt,
+			Rank:               result.Rank,
+			TitleSnippet:       result.TitleSnippet,
+			DescriptionSnippet: result.DescriptionSnippet,
+		})
+	}
+
+	respondWithJSON(w, 200, posts)
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestBuildTsQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{"single word", "golang", "golang"},
+		{"words are anded", "golang rss", "golang & rss"},
+		{"phrase", `"static site"`, "(static <-> site)"},
+		{"unclosed phrase", `"static site`, "(static <-> site)"},
+		{"prefix", "feed*", "feed:*"},
+		{"negation", "-spam", "!spam"},
+		{"negated prefix", "-spam*", "!spam:*"},
+		{"mixed", `go "static site" -wordpress`, "go & (static <-> site) & !wordpress"},
+		{"punctuation stripped", "it's c++", "its & c"},
+		{"tsquery syntax stripped", "a&b | !c (d) e:x", "ab & c & d & ex"},
+		{"phrase punctuation stripped", `"don't stop"`, "(dont <-> stop)"},
+		{"unicode letters kept", "naïve café", "naïve & café"},
+		{"operators alone", "- * & |", ""},
+		{"empty phrase", `""`, ""},
+		{"blank", "   ", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildTsQuery(tt.q); got != tt.want {
+				t.Errorf("buildTsQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saubuny/bootdev-rss/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	accessTokenLifetime  = 15 * time.Minute
+	refreshTokenLifetime = 30 * 24 * time.Hour
+	minPasswordLength    = 8
+)
+
+// Access tokens are compact HS256 JWTs, so any JWT library can read them
+type accessTokenClaims struct {
+	Subject   uuid.UUID `json:"sub"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+var accessTokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (cfg *apiConfig) signAccessToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims, err := json.Marshal(accessTokenClaims{
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := accessTokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + cfg.signTokenPart(unsigned), nil
+}
+
+func (cfg *apiConfig) signTokenPart(unsigned string) string {
+	mac := hmac.New(sha256.New, cfg.JWTSecret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Returns the user an access token was issued to, if it is ours and still valid
+func (cfg *apiConfig) verifyAccessToken(token string) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != accessTokenHeader {
+		return uuid.Nil, errors.New("Malformed Token")
+	}
+
+	expected := cfg.signTokenPart(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return uuid.Nil, errors.New("Invalid Token")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return uuid.Nil, errors.New("Malformed Token")
+	}
+
+	var claims accessTokenClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return uuid.Nil, errors.New("Malformed Token")
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return uuid.Nil, errors.New("Token expired")
+	}
+
+	return claims.Subject, nil
+}
+
+type session struct {
+	AccessToken  string    `json:"access_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+// Issues a fresh access and refresh token pair for the user
+func (cfg *apiConfig) createSession(ctx context.Context, q *database.Queries, userID uuid.UUID) (session, error) {
+	accessToken, err := cfg.signAccessToken(userID)
+	if err != nil {
+		return session{}, &apiError{Status: 500, Code: codeInternal, Message: "Error Signing Access Token: " + err.Error()}
+	}
+
+	// Same shape as an API key, and stored the same way
+	refreshToken, err := generateApiKey()
+	if err != nil {
+		return session{}, dbError(err, "Error Creating Refresh Token")
+	}
+
+	now := time.Now()
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenLifetime),
+		TokenHash: hashApiKey(refreshToken),
+	})
+	if err != nil {
+		return session{}, dbError(err, "Error Creating Refresh Token")
+	}
+
+	return session{
+		AccessToken:  accessToken,
+		ExpiresAt:    now.Add(accessTokenLifetime),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Compared against when there's no user to check, so the response takes as
+// long as a wrong password would
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not anyone's password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func hashPassword(password string) (sql.NullString, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
+	type body struct {
+		Username string `json:"username" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	// Same answer, in the same time, for unknown users and wrong passwords
+	user, err := cfg.DB.GetUserByUsername(r.Context(), sql.NullString{String: b.Username, Valid: true})
+	if err != nil || !user.PasswordHash.Valid {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(b.Password))
+		respondWithError(w, 401, "Invalid username or password")
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(b.Password))
+	if err != nil {
+		respondWithError(w, 401, "Invalid username or password")
+		return
+	}
+
+	sess, err := cfg.createSession(r.Context(), cfg.DB, user.ID)
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Creating Session"))
+		return
+	}
+
+	respondWithJSON(w, 200, sess)
+}
+
+// Trades a refresh token for a new session. Refresh tokens are single use:
+// the token is deleted in the same transaction the new session is created in,
+// so of two refreshes racing with one token only one gets through, and a
+// failed refresh leaves the token usable.
+func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	type body struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	var sess session
+	expired := false
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		token, err := q.ConsumeRefreshToken(r.Context(), hashApiKey(b.RefreshToken))
+		if errors.Is(err, sql.ErrNoRows) {
+			return &apiError{Status: 401, Code: codeUnauthorized, Message: "Invalid refresh token"}
+		}
+		if err != nil {
+			return dbError(err, "Error Deleting Refresh Token")
+		}
+
+		// Committed so the expired token is gone
+		if time.Now().After(token.ExpiresAt) {
+			expired = true
+			return nil
+		}
+
+		user, err := q.GetUserById(r.Context(), token.UserID)
+		if err != nil {
+			return dbError(err, "Error Getting User")
+		}
+		if user.DisabledAt.Valid {
+			return &apiError{Status: 403, Code: codeAccountDisabled, Message: "Account disabled"}
+		}
+
+		sess, err = cfg.createSession(r.Context(), q, user.ID)
+		return err
+	})
+	if err != nil {
+		respondWithApiError(w, asApiError(err, "Error Refreshing Session"))
+		return
+	}
+	if expired {
+		respondWithApiError(w, &apiError{Status: 401, Code: codeUnauthorized, Message: "Refresh token expired"})
+		return
+	}
+
+	respondWithJSON(w, 200, sess)
+}
+
+func (cfg *apiConfig) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	type body struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	var b body
+	if apiErr := decodeBody(w, r, &b); apiErr != nil {
+		respondWithApiError(w, apiErr)
+		return
+	}
+
+	_, err := cfg.DB.ConsumeRefreshToken(r.Context(), hashApiKey(b.RefreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 401, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		respondWithApiError(w, dbError(err, "Error Deleting Refresh Token"))
+		return
+	}
+
+	w.WriteHeader(200)
+}
@@ -0,0 +1,27 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (id, user_id, created_at, name, prefix, key_hash, expires_at, scopes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetApiKeyByHash :one
+SELECT * FROM api_keys WHERE key_hash = $1;
+
+-- name: GetApiKeyById :one
+SELECT * FROM api_keys WHERE id = $1;
+
+-- name: GetApiKeysByUserId :many
+SELECT * FROM api_keys WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: TouchApiKey :exec
+UPDATE api_keys
+SET last_used_at = $1
+WHERE id = $2;
+
+-- name: ExpireApiKey :exec
+UPDATE api_keys
+SET expires_at = $1
+WHERE id = $2;
+
+-- name: DeleteApiKey :exec
+DELETE FROM api_keys WHERE id = $1;
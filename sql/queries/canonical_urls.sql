@@ -0,0 +1,35 @@
+-- name: GetFeedUrls :many
+SELECT id, url, COALESCE(canonical_url, '')::text AS canonical_url, created_at FROM feeds
+ORDER BY created_at, id;
+
+-- name: SetFeedCanonicalUrl :exec
+UPDATE feeds SET canonical_url = $1 WHERE id = $2;
+
+-- name: GetPostUrlsAfter :many
+SELECT id, url, COALESCE(canonical_url, '')::text AS canonical_url, created_at FROM posts
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: SetPostCanonicalUrl :exec
+UPDATE posts SET canonical_url = $1 WHERE id = $2;
+
+-- name: GetPostByCanonicalUrl :one
+SELECT id, created_at FROM posts WHERE canonical_url = $1;
+
+-- name: MovePostStars :exec
+UPDATE starred_posts SET post_id = sqlc.arg(to_post_id)
+WHERE post_id = sqlc.arg(from_post_id)
+AND user_id NOT IN (
+    SELECT user_id FROM starred_posts WHERE post_id = sqlc.arg(to_post_id)
+);
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1;
+
+-- name: GetFeedUrlAliases :many
+SELECT * FROM feed_url_aliases
+ORDER BY created_at;
+
+-- name: DeleteFeedUrlAlias :exec
+DELETE FROM feed_url_aliases WHERE url = $1;
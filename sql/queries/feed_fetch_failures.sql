@@ -0,0 +1,15 @@
+-- name: RecordFeedFetchFailure :one
+INSERT INTO feed_fetch_failures (feed_id, failures, last_error, failed_at, next_attempt_at)
+VALUES ($1, 1, $2, $3, $3)
+ON CONFLICT (feed_id) DO UPDATE
+SET failures = feed_fetch_failures.failures + 1,
+    last_error = EXCLUDED.last_error,
+    failed_at = EXCLUDED.failed_at
+RETURNING *;
+
+-- name: SetFeedNextAttempt :exec
+UPDATE feed_fetch_failures SET next_attempt_at = $1
+WHERE feed_id = $2;
+
+-- name: ClearFeedFetchFailures :exec
+DELETE FROM feed_fetch_failures WHERE feed_id = $1;
@@ -0,0 +1,27 @@
+-- name: GetFeedFetchSettings :one
+SELECT * FROM feed_fetch_settings WHERE feed_id = $1;
+
+-- name: UpsertFeedFetchSettings :one
+INSERT INTO feed_fetch_settings (feed_id, headers, credentials_kind, credentials, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (feed_id) DO UPDATE
+SET headers = EXCLUDED.headers,
+    credentials_kind = EXCLUDED.credentials_kind,
+    credentials = EXCLUDED.credentials,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: DeleteFeedFetchSettings :exec
+DELETE FROM feed_fetch_settings WHERE feed_id = $1;
+
+-- name: ClearFeedFetchCredentials :exec
+UPDATE feed_fetch_settings
+SET credentials_kind = NULL, credentials = NULL, updated_at = $1
+WHERE feed_id = $2;
+
+-- name: IsFeedPrivate :one
+SELECT EXISTS (
+    SELECT 1 FROM feed_fetch_settings
+    WHERE feed_id = $1
+    AND credentials IS NOT NULL
+);
@@ -0,0 +1,20 @@
+-- name: UpsertFeedFollowCredentials :one
+INSERT INTO feed_follow_credentials (feed_follow_id, kind, encrypted, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $4)
+ON CONFLICT (feed_follow_id) DO UPDATE
+SET kind = EXCLUDED.kind,
+    encrypted = EXCLUDED.encrypted,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: GetFeedFollowCredentials :one
+SELECT * FROM feed_follow_credentials WHERE feed_follow_id = $1;
+
+-- name: DeleteFeedFollowCredentials :exec
+DELETE FROM feed_follow_credentials WHERE feed_follow_id = $1;
+
+-- name: DeleteFeedFollowCredentialsByFeedId :exec
+DELETE FROM feed_follow_credentials
+USING feed_follows
+WHERE feed_follows.id = feed_follow_credentials.feed_follow_id
+AND feed_follows.feed_id = $1;
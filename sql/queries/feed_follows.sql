@@ -1,13 +1,35 @@
 -- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING
 RETURNING *;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows WHERE id = $1;
 
+-- name: DeleteFeedFollowByFeedId :one
+DELETE FROM feed_follows WHERE user_id = $1 AND feed_id = $2
+RETURNING *;
+
 -- name: GetFeedFollowById :one
 SELECT * FROM feed_follows WHERE id = $1;
 
+-- name: GetFeedFollowByIdForUpdate :one
+SELECT * FROM feed_follows WHERE id = $1
+FOR UPDATE;
+
+-- name: GetFeedFollowByUserAndFeed :one
+SELECT * FROM feed_follows WHERE user_id = $1 AND feed_id = $2;
+
 -- name: GetFeedFollowsByUserId :many
-SELECT * FROM feed_follows WHERE user_id = $1;
+SELECT feed_follows.*, feeds.name AS feed_name, feeds.url AS feed_url FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.pinned DESC, feed_follows.created_at;
+
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET custom_title = $1, hidden_from_timeline = $2, pinned = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
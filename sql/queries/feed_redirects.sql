@@ -0,0 +1,85 @@
+-- name: RecordFeedRedirect :one
+INSERT INTO feed_redirects (feed_id, url, seen_count, first_seen_at, last_seen_at)
+VALUES (sqlc.arg(feed_id), sqlc.arg(url), 1, sqlc.arg(seen_at), sqlc.arg(seen_at))
+ON CONFLICT (feed_id) DO UPDATE
+SET seen_count = CASE WHEN feed_redirects.url = EXCLUDED.url THEN feed_redirects.seen_count + 1 ELSE 1 END,
+    first_seen_at = CASE WHEN feed_redirects.url = EXCLUDED.url THEN feed_redirects.first_seen_at ELSE EXCLUDED.first_seen_at END,
+    url = EXCLUDED.url,
+    last_seen_at = EXCLUDED.last_seen_at
+RETURNING *;
+
+-- name: ClearFeedRedirect :exec
+DELETE FROM feed_redirects WHERE feed_id = $1;
+
+-- name: CreateFeedUrlAlias :exec
+INSERT INTO feed_url_aliases (url, feed_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id;
+
+-- name: GetFeedByAlias :one
+SELECT feeds.* FROM feeds
+INNER JOIN feed_url_aliases
+ON feed_url_aliases.feed_id = feeds.id
+WHERE feed_url_aliases.url = $1;
+
+-- name: MoveFeedUrlAliases :exec
+UPDATE feed_url_aliases SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: MergeFeedFollowFolders :exec
+INSERT INTO folder_feed_follows (folder_id, feed_follow_id, created_at)
+SELECT folder_feed_follows.folder_id, kept.id, folder_feed_follows.created_at
+FROM folder_feed_follows
+INNER JOIN feed_follows old
+ON old.id = folder_feed_follows.feed_follow_id
+INNER JOIN feed_follows kept
+ON kept.user_id = old.user_id AND kept.feed_id = sqlc.arg(to_feed_id)
+WHERE old.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT DO NOTHING;
+
+-- name: MergeFeedFollowTitles :exec
+UPDATE feed_follows kept
+SET custom_title = old.custom_title, updated_at = sqlc.arg(updated_at)
+FROM feed_follows old
+WHERE old.feed_id = sqlc.arg(from_feed_id)
+AND kept.feed_id = sqlc.arg(to_feed_id)
+AND kept.user_id = old.user_id
+AND kept.custom_title IS NULL
+AND old.custom_title IS NOT NULL;
+
+-- name: MergeFeedFollowCredentials :exec
+INSERT INTO feed_follow_credentials (feed_follow_id, kind, encrypted, created_at, updated_at)
+SELECT kept.id, feed_follow_credentials.kind, feed_follow_credentials.encrypted, feed_follow_credentials.created_at, feed_follow_credentials.updated_at
+FROM feed_follow_credentials
+INNER JOIN feed_follows old
+ON old.id = feed_follow_credentials.feed_follow_id
+INNER JOIN feed_follows kept
+ON kept.user_id = old.user_id AND kept.feed_id = sqlc.arg(to_feed_id)
+WHERE old.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (feed_follow_id) DO NOTHING;
+
+-- name: MoveFeedFetchSettings :exec
+UPDATE feed_fetch_settings SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_fetch_settings WHERE feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows WHERE feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: MoveFeedPosts :exec
+UPDATE posts SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: MoveFeedRules :exec
+UPDATE rules SET feed_id = sqlc.arg(to_feed_id)::uuid, updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id)::uuid;
+
+-- name: MoveFeedSavedSearches :exec
+UPDATE saved_searches SET feed_id = sqlc.arg(to_feed_id)::uuid, updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id)::uuid;
@@ -1,17 +1,77 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, user_id, created_at, updated_at, name, url)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, user_id, created_at, updated_at, name, url, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetAllFeeds :many
 SELECT * FROM feeds;
 
 -- name: GetNextFeedsToFetch :many
-SELECT * FROM feeds
-ORDER BY last_fetched_at
-LIMIT $1;
+SELECT feeds.* FROM feeds
+LEFT JOIN feed_fetch_failures
+ON feed_fetch_failures.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (feed_fetch_failures.next_attempt_at IS NULL OR feed_fetch_failures.next_attempt_at <= sqlc.arg(now))
+ORDER BY feeds.last_fetched_at NULLS FIRST
+LIMIT sqlc.arg('limit');
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds 
 SET last_fetched_at = $1, updated_at = $2
 WHERE id = $3;
+
+-- name: GetFeedById :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: SetFeedDisabled :exec
+UPDATE feeds
+SET disabled_at = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: UpdateFeed :one
+UPDATE feeds
+SET name = $1, url = $2, canonical_url = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
+
+-- name: CountOtherFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+AND can_read_feed(user_id, feed_id);
+
+-- name: TransferFeedOwnership :one
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> feeds.user_id
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = $1
+WHERE id = $2
+RETURNING *;
+
+-- name: ReassignFeedsFromUser :exec
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> sqlc.arg(user_id)
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = sqlc.arg(updated_at)
+WHERE feeds.user_id = sqlc.arg(user_id)
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> sqlc.arg(user_id)
+    AND can_read_feed(feed_follows.user_id, feeds.id)
+);
+
+-- name: GetFeedByCanonicalUrl :one
+SELECT * FROM feeds WHERE canonical_url = $1;
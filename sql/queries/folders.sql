@@ -0,0 +1,70 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, user_id, created_at, updated_at, name, last_read_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetFolderById :one
+SELECT * FROM folders WHERE id = $1;
+
+-- name: GetFoldersByUserId :many
+SELECT * FROM folders WHERE user_id = $1
+ORDER BY name;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: MarkFolderRead :exec
+UPDATE folders
+SET last_read_at = $1, updated_at = $1
+WHERE id = $2;
+
+-- name: DeleteFolder :exec
+DELETE FROM folders WHERE id = $1;
+
+-- name: AddFeedFollowToFolder :exec
+INSERT INTO folder_feed_follows (folder_id, feed_follow_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFeedFollowFromFolder :exec
+DELETE FROM folder_feed_follows
+WHERE folder_id = $1 AND feed_follow_id = $2;
+
+-- name: GetFolderFeedFollowsByUserId :many
+SELECT folder_feed_follows.* FROM folder_feed_follows
+INNER JOIN folders
+ON folders.id = folder_feed_follows.folder_id
+WHERE folders.user_id = $1;
+
+-- name: GetFolderFeedFollowIds :many
+SELECT feed_follow_id FROM folder_feed_follows
+WHERE folder_id = $1;
+
+-- name: GetFolderUnreadCounts :many
+SELECT folders.id, COUNT(DISTINCT posts.id) FILTER (
+    WHERE can_read_feed(folders.user_id, posts.feed_id)
+) AS unread_count
+FROM folders
+LEFT JOIN folder_feed_follows
+ON folder_feed_follows.folder_id = folders.id
+LEFT JOIN feed_follows
+ON feed_follows.id = folder_feed_follows.feed_follow_id
+LEFT JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+AND posts.created_at > folders.last_read_at
+WHERE folders.user_id = $1
+GROUP BY folders.id;
+
+-- name: GetFolderPosts :many
+SELECT DISTINCT posts.* FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN folder_feed_follows
+ON folder_feed_follows.feed_follow_id = feed_follows.id
+WHERE folder_feed_follows.folder_id = $1
+AND can_read_feed(feed_follows.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,33 @@
+-- name: CreatePostFingerprint :exec
+INSERT INTO post_fingerprints (post_id, fingerprint, cluster_id, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreatePostFingerprintBands :exec
+INSERT INTO post_fingerprint_bands (post_id, band_key)
+SELECT sqlc.arg(post_id)::uuid, unnest(sqlc.arg(band_keys)::int[]);
+
+-- name: GetFingerprintCandidates :many
+SELECT DISTINCT post_fingerprints.* FROM post_fingerprints
+INNER JOIN post_fingerprint_bands
+ON post_fingerprint_bands.post_id = post_fingerprints.post_id
+INNER JOIN posts
+ON posts.id = post_fingerprints.post_id
+WHERE post_fingerprint_bands.band_key = ANY(sqlc.arg(band_keys)::int[])
+AND posts.published_at BETWEEN sqlc.arg(published_after) AND sqlc.arg(published_before)
+AND posts.feed_id <> sqlc.arg(feed_id);
+
+-- name: GetPostClusters :many
+SELECT post_id, cluster_id FROM post_fingerprints
+WHERE post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetClusterPostsForUser :many
+SELECT DISTINCT posts.*, post_fingerprints.cluster_id FROM posts
+INNER JOIN post_fingerprints
+ON post_fingerprints.post_id = posts.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND NOT feed_follows.hidden_from_timeline
+AND can_read_feed(feed_follows.user_id, posts.feed_id)
+AND post_fingerprints.cluster_id = ANY(sqlc.arg(cluster_ids)::uuid[])
+ORDER BY posts.published_at DESC;
@@ -1,13 +1,65 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetPostsByUser :many
+SELECT DISTINCT posts.* FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT feed_follows.hidden_from_timeline
+AND can_read_feed(feed_follows.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3;
+
+-- name: SearchPostsByUser :many
+SELECT matches.*,
+    ts_headline(
+        'english',
+        replace(replace(replace(matches.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    ) AS title_snippet,
+    ts_headline(
+        'english',
+        replace(replace(regexp_replace(matches.description, '<[^>]*>', ' ', 'g'), '<', '&lt;'), '>', '&gt;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+    ) AS description_snippet
+FROM (
+    SELECT posts.*,
+        ts_rank_cd(
+            setweight(to_tsvector('english', posts.title), 'A') ||
+            setweight(to_tsvector('english', posts.description), 'B'),
+            query
+        )::real AS rank
+    FROM posts
+    INNER JOIN feed_follows
+    ON posts.feed_id = feed_follows.feed_id
+    CROSS JOIN to_tsquery('english', sqlc.arg(query)) query
+    WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND can_read_feed(feed_follows.user_id, posts.feed_id)
+    AND (
+        setweight(to_tsvector('english', posts.title), 'A') ||
+        setweight(to_tsvector('english', posts.description), 'B')
+    ) @@ query
+    ORDER BY rank DESC, posts.published_at DESC, posts.id
+    LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset')
+) matches
+CROSS JOIN to_tsquery('english', sqlc.arg(query)) query
+ORDER BY matches.rank DESC, matches.published_at DESC, matches.id;
+
+-- name: StarPost :exec
+INSERT INTO starred_posts (user_id, post_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: GetStarredPostsByUser :many
 SELECT posts.* FROM posts
-INNER JOIN feeds
-ON posts.feed_id = feeds.id
-INNER JOIN users
-ON feeds.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
+INNER JOIN starred_posts
+ON starred_posts.post_id = posts.id
+WHERE starred_posts.user_id = $1
+AND can_read_feed(starred_posts.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3;
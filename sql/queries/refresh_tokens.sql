@@ -0,0 +1,11 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (id, user_id, created_at, expires_at, token_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ConsumeRefreshToken :one
+DELETE FROM refresh_tokens WHERE token_hash = $1
+RETURNING *;
+
+-- name: DeleteRefreshTokensByUser :exec
+DELETE FROM refresh_tokens WHERE user_id = $1;
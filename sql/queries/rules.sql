@@ -0,0 +1,33 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, user_id, feed_id, created_at, updated_at, name, field, pattern, action)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetRuleById :one
+SELECT * FROM rules WHERE id = $1;
+
+-- name: GetRulesByUserId :many
+SELECT * FROM rules WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetRulesForFeed :many
+SELECT rules.* FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = $1
+AND (rules.feed_id IS NULL OR rules.feed_id = feed_follows.feed_id)
+AND can_read_feed(rules.user_id, feed_follows.feed_id);
+
+-- name: UpdateRule :one
+UPDATE rules
+SET feed_id = $1, updated_at = $2, name = $3, field = $4, pattern = $5, action = $6
+WHERE id = $7
+RETURNING *;
+
+-- name: IncrementRuleHits :exec
+UPDATE rules
+SET hit_count = hit_count + 1
+WHERE id = $1;
+
+-- name: DeleteRule :exec
+DELETE FROM rules WHERE id = $1;
@@ -0,0 +1,74 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, user_id, feed_id, created_at, updated_at, last_read_at, name, query, ts_query)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetSavedSearchById :one
+SELECT * FROM saved_searches WHERE id = $1;
+
+-- name: GetSavedSearchesByUserId :many
+SELECT saved_searches.*, COUNT(saved_search_posts.post_id) FILTER (
+    WHERE saved_search_posts.created_at > saved_searches.last_read_at
+    AND can_read_feed(saved_searches.user_id, posts.feed_id)
+) AS unread_count
+FROM saved_searches
+LEFT JOIN saved_search_posts
+ON saved_search_posts.saved_search_id = saved_searches.id
+LEFT JOIN posts
+ON posts.id = saved_search_posts.post_id
+WHERE saved_searches.user_id = $1
+GROUP BY saved_searches.id
+ORDER BY saved_searches.name;
+
+-- name: MarkSavedSearchRead :exec
+UPDATE saved_searches
+SET last_read_at = $1, updated_at = $1
+WHERE id = $2;
+
+-- name: DeleteSavedSearch :exec
+DELETE FROM saved_searches WHERE id = $1;
+
+-- name: GetSavedSearchPosts :many
+SELECT posts.* FROM posts
+INNER JOIN saved_search_posts
+ON saved_search_posts.post_id = posts.id
+INNER JOIN saved_searches
+ON saved_searches.id = saved_search_posts.saved_search_id
+WHERE saved_search_posts.saved_search_id = $1
+AND can_read_feed(saved_searches.user_id, posts.feed_id)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3;
+
+-- name: MatchSavedSearchAgainstPosts :exec
+INSERT INTO saved_search_posts (saved_search_id, post_id, created_at)
+SELECT saved_searches.id, posts.id, saved_searches.created_at
+FROM saved_searches
+INNER JOIN feed_follows
+ON feed_follows.user_id = saved_searches.user_id
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+WHERE saved_searches.id = $1
+AND (saved_searches.feed_id IS NULL OR saved_searches.feed_id = posts.feed_id)
+AND can_read_feed(saved_searches.user_id, posts.feed_id)
+AND (
+    setweight(to_tsvector('english', posts.title), 'A') ||
+    setweight(to_tsvector('english', posts.description), 'B')
+) @@ to_tsquery('english', saved_searches.ts_query)
+ON CONFLICT DO NOTHING;
+
+-- name: MatchPostAgainstSavedSearches :exec
+INSERT INTO saved_search_posts (saved_search_id, post_id, created_at)
+SELECT saved_searches.id, posts.id, sqlc.arg(matched_at)::timestamp
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN saved_searches
+ON saved_searches.user_id = feed_follows.user_id
+WHERE posts.id = sqlc.arg(post_id)
+AND (saved_searches.feed_id IS NULL OR saved_searches.feed_id = posts.feed_id)
+AND can_read_feed(saved_searches.user_id, posts.feed_id)
+AND (
+    setweight(to_tsvector('english', posts.title), 'A') ||
+    setweight(to_tsvector('english', posts.description), 'B')
+) @@ to_tsquery('english', saved_searches.ts_query)
+ON CONFLICT DO NOTHING;
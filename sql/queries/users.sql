@@ -1,8 +1,28 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name, api_key)
-VALUES ($1, $2, $3, $4, encode(sha256(random()::text::bytea), 'hex'))
+INSERT INTO users (id, created_at, updated_at, name, username, password_hash)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
--- name: GetUserByApiKey :one
-SELECT * FROM users WHERE api_key = $1;
+-- name: GetUserById :one
+SELECT * FROM users WHERE id = $1;
 
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE username = $1;
+
+-- name: GetAllUsers :many
+SELECT * FROM users
+ORDER BY created_at;
+
+-- name: SetUserDisabled :exec
+UPDATE users
+SET disabled_at = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: UpdateUser :one
+UPDATE users
+SET name = $1, username = $2, password_hash = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
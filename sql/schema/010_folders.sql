@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE folder_feed_follows (
+    folder_id UUID NOT NULL,
+    feed_follow_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(folder_id, feed_follow_id),
+    FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    FOREIGN KEY(feed_follow_id) REFERENCES feed_follows(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE folder_feed_follows;
+DROP TABLE folders;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD custom_title TEXT,
+ADD hidden_from_timeline BOOLEAN NOT NULL DEFAULT FALSE,
+ADD pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN custom_title,
+DROP COLUMN hidden_from_timeline,
+DROP COLUMN pinned;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO api_keys (id, user_id, created_at, name, prefix, key_hash)
+SELECT gen_random_uuid(), id, created_at, 'default', left(api_key, 8), encode(sha256(convert_to(api_key, 'UTF8')), 'hex')
+FROM users;
+
+ALTER TABLE users DROP COLUMN api_key;
+
+-- +goose Down
+ALTER TABLE users
+ADD api_key VARCHAR(64) UNIQUE NOT NULL
+DEFAULT encode(sha256(random()::text::bytea), 'hex');
+
+DROP TABLE api_keys;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE api_keys
+ADD expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN expires_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE api_keys
+ADD scopes TEXT[] NOT NULL DEFAULT '{read,write}';
+
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN scopes;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD username TEXT UNIQUE,
+ADD password_hash TEXT;
+
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE refresh_tokens;
+
+ALTER TABLE users
+DROP COLUMN username,
+DROP COLUMN password_hash;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users
+ADD is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+ADD disabled_at TIMESTAMP;
+
+ALTER TABLE feeds
+ADD disabled_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN disabled_at;
+
+ALTER TABLE users
+DROP COLUMN is_admin,
+DROP COLUMN disabled_at;
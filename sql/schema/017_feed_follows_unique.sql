@@ -0,0 +1,47 @@
+-- +goose Up
+-- Keep the oldest follow where a user followed the same feed more than once.
+-- The folders the others were in and their custom title move over to it.
+WITH kept AS (
+    SELECT DISTINCT ON (user_id, feed_id) id, user_id, feed_id FROM feed_follows
+    ORDER BY user_id, feed_id, created_at, id
+)
+INSERT INTO folder_feed_follows (folder_id, feed_follow_id, created_at)
+SELECT folder_feed_follows.folder_id, kept.id, folder_feed_follows.created_at
+FROM folder_feed_follows
+INNER JOIN feed_follows
+ON feed_follows.id = folder_feed_follows.feed_follow_id
+INNER JOIN kept
+ON kept.user_id = feed_follows.user_id AND kept.feed_id = feed_follows.feed_id
+WHERE feed_follows.id <> kept.id
+ON CONFLICT DO NOTHING;
+
+-- The newest custom title wins if the kept follow has none
+UPDATE feed_follows a
+SET custom_title = (
+    SELECT b.custom_title FROM feed_follows b
+    WHERE b.user_id = a.user_id
+    AND b.feed_id = a.feed_id
+    AND b.custom_title IS NOT NULL
+    ORDER BY b.created_at DESC, b.id DESC
+    LIMIT 1
+)
+WHERE a.custom_title IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows b
+    WHERE b.user_id = a.user_id
+    AND b.feed_id = a.feed_id
+    AND (b.created_at, b.id) < (a.created_at, a.id)
+);
+
+DELETE FROM feed_follows a
+USING feed_follows b
+WHERE a.user_id = b.user_id
+AND a.feed_id = b.feed_id
+AND (a.created_at, a.id) > (b.created_at, b.id);
+
+ALTER TABLE feed_follows
+ADD CONSTRAINT feed_follows_user_id_feed_id_key UNIQUE (user_id, feed_id);
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP CONSTRAINT feed_follows_user_id_feed_id_key;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- The permanent redirect a feed has been answering with, until we trust it
+-- enough to move the feed
+CREATE TABLE feed_redirects (
+    feed_id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    seen_count INTEGER NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Old URLs of feeds that moved, so adding them again finds the feed
+CREATE TABLE feed_url_aliases (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_url_aliases;
+DROP TABLE feed_redirects;
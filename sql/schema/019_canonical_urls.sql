@@ -0,0 +1,11 @@
+-- +goose Up
+-- Canonicalisation happens in the app, so existing rows are filled in by
+-- running the server binary with backfill-canonical-urls, which also merges
+-- rows that turn out to be the same feed or post. It can run before or after
+-- 024, which makes the column required and unique.
+ALTER TABLE feeds ADD canonical_url TEXT;
+ALTER TABLE posts ADD canonical_url TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN canonical_url;
+ALTER TABLE feeds DROP COLUMN canonical_url;
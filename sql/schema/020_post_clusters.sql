@@ -0,0 +1,15 @@
+-- +goose Up
+-- SimHash of each post's text and the cluster of near-duplicate posts it
+-- belongs to. A cluster's ID is the ID of the first post in it.
+CREATE TABLE post_fingerprints (
+    post_id UUID PRIMARY KEY,
+    fingerprint BIGINT NOT NULL,
+    cluster_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_fingerprints_cluster_id_idx ON post_fingerprints (cluster_id);
+
+-- +goose Down
+DROP TABLE post_fingerprints;
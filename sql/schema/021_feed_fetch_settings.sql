@@ -0,0 +1,13 @@
+-- +goose Up
+-- Per-feed tweaks to how the fetcher requests a feed
+CREATE TABLE feed_fetch_settings (
+    feed_id UUID PRIMARY KEY,
+    headers JSONB NOT NULL DEFAULT '{}',
+    basic_auth_username TEXT,
+    basic_auth_password TEXT,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_fetch_settings;
@@ -0,0 +1,52 @@
+-- +goose Up
+-- Credentials a user supplied for a feed they follow, encrypted by the app
+-- with CREDENTIALS_KEY. Only the kind is stored in the clear.
+CREATE TABLE feed_follow_credentials (
+    feed_follow_id UUID PRIMARY KEY,
+    kind TEXT NOT NULL,
+    encrypted BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(feed_follow_id) REFERENCES feed_follows(id) ON DELETE CASCADE
+);
+
+-- Feed-level basic auth was stored in plain text, so it has to be set again
+ALTER TABLE feed_fetch_settings
+    DROP COLUMN basic_auth_username,
+    DROP COLUMN basic_auth_password,
+    ADD COLUMN credentials_kind TEXT,
+    ADD COLUMN credentials BYTEA;
+
+-- A feed is private once its owner or an admin sets credentials in its fetch
+-- settings. Its posts are then only readable by the owner and by followers
+-- whose own credentials got through to the feed when they saved them.
+-- +goose StatementBegin
+CREATE FUNCTION can_read_feed(reader UUID, feed UUID) RETURNS BOOLEAN AS $$
+    SELECT NOT EXISTS (
+        SELECT 1 FROM feed_fetch_settings
+        WHERE feed_fetch_settings.feed_id = feed
+        AND feed_fetch_settings.credentials IS NOT NULL
+    ) OR EXISTS (
+        SELECT 1 FROM feeds
+        WHERE feeds.id = feed
+        AND feeds.user_id = reader
+    ) OR EXISTS (
+        SELECT 1 FROM feed_follow_credentials
+        INNER JOIN feed_follows
+        ON feed_follows.id = feed_follow_credentials.feed_follow_id
+        WHERE feed_follows.feed_id = feed
+        AND feed_follows.user_id = reader
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION can_read_feed(UUID, UUID);
+
+ALTER TABLE feed_fetch_settings
+    DROP COLUMN credentials_kind,
+    DROP COLUMN credentials,
+    ADD COLUMN basic_auth_username TEXT,
+    ADD COLUMN basic_auth_password TEXT;
+
+DROP TABLE feed_follow_credentials;
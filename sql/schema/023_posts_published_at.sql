@@ -0,0 +1,6 @@
+-- +goose Up
+-- Clustering looks up every post published within a couple of days of a new one
+CREATE INDEX posts_published_at_idx ON posts (published_at);
+
+-- +goose Down
+DROP INDEX posts_published_at_idx;
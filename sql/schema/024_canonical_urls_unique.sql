@@ -0,0 +1,40 @@
+-- +goose Up
+-- Rows backfill-canonical-urls hasn't reached yet fall back to their own URL,
+-- which was unique, until it's run. The only thing that can collide is a row
+-- given that exact canonical URL since 019, so those get a placeholder that
+-- can never be a canonical URL (canonical URLs have no fragment) and the
+-- backfill merges them later.
+UPDATE feeds SET canonical_url = CASE
+    WHEN EXISTS (SELECT 1 FROM feeds other WHERE other.canonical_url = feeds.url)
+    THEN feeds.url || '#' || feeds.id
+    ELSE feeds.url
+END
+WHERE canonical_url IS NULL;
+
+UPDATE posts SET canonical_url = CASE
+    WHEN EXISTS (SELECT 1 FROM posts other WHERE other.canonical_url = posts.url)
+    THEN posts.url || '#' || posts.id
+    ELSE posts.url
+END
+WHERE canonical_url IS NULL;
+
+ALTER TABLE feeds
+ALTER COLUMN canonical_url SET NOT NULL,
+DROP CONSTRAINT feeds_url_key,
+ADD CONSTRAINT feeds_canonical_url_key UNIQUE (canonical_url);
+
+ALTER TABLE posts
+ALTER COLUMN canonical_url SET NOT NULL,
+DROP CONSTRAINT posts_url_key,
+ADD CONSTRAINT posts_canonical_url_key UNIQUE (canonical_url);
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT posts_canonical_url_key,
+ADD CONSTRAINT posts_url_key UNIQUE (url),
+ALTER COLUMN canonical_url DROP NOT NULL;
+
+ALTER TABLE feeds
+DROP CONSTRAINT feeds_canonical_url_key,
+ADD CONSTRAINT feeds_url_key UNIQUE (url),
+ALTER COLUMN canonical_url DROP NOT NULL;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Feeds whose last fetches failed, and when to try them again. Cleared on the
+-- next successful fetch.
+CREATE TABLE feed_fetch_failures (
+    feed_id UUID PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_error TEXT NOT NULL,
+    failed_at TIMESTAMP NOT NULL,
+    next_attempt_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_fetch_failures;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Posts fetched into a folder's feeds after this are unread. Existing folders
+-- start from when they were created.
+ALTER TABLE folders ADD last_read_at TIMESTAMP;
+UPDATE folders SET last_read_at = created_at;
+ALTER TABLE folders ALTER COLUMN last_read_at SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE folders DROP COLUMN last_read_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Clustering looks candidates up by these keys instead of comparing against
+-- every fingerprint in the window. A key is a pair of a fingerprint's eight
+-- bytes: which pair, then the two byte values. Fingerprints no more than 6
+-- bits apart differ in at most 6 bytes, so they always share a key.
+CREATE TABLE post_fingerprint_bands (
+    post_id UUID NOT NULL,
+    band_key INTEGER NOT NULL,
+    PRIMARY KEY (post_id, band_key),
+    FOREIGN KEY(post_id) REFERENCES post_fingerprints(post_id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_fingerprint_bands_band_key_idx ON post_fingerprint_bands (band_key);
+
+-- Same keys as simHashBands, the pair index counts (0,1), (0,2) ... (6,7)
+INSERT INTO post_fingerprint_bands (post_id, band_key)
+SELECT post_fingerprints.post_id,
+    ((i * (15 - i) / 2 + j - i - 1) << 16)
+    | ((((post_fingerprints.fingerprint >> (8 * i)) & 255)::int) << 8)
+    | (((post_fingerprints.fingerprint >> (8 * j)) & 255)::int)
+FROM post_fingerprints
+CROSS JOIN generate_series(0, 7) i
+CROSS JOIN generate_series(0, 7) j
+WHERE i < j;
+
+-- +goose Down
+DROP TABLE post_fingerprint_bands;
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+
+	"github.com/saubuny/bootdev-rss/internal/database"
+)
+
+// Runs fn with queries bound to a single transaction. The transaction commits
+// if fn returns nil and rolls back otherwise, so fn should go through q and
+// never cfg.DB.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(cfg.DB.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
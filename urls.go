@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Reduces a URL to the form we deduplicate feeds and posts on, so that
+// http/https, mixed-case hosts, default ports, trailing slashes, fragments
+// and utm_* tracking parameters don't make the same thing look different.
+// The canonical URL is only for comparing; we always fetch and link the
+// original. Anything that doesn't parse is used as is.
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" {
+		scheme = "https"
+	}
+	u.Scheme = scheme
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literals keep their brackets without a port too
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	// Work on the escaped path so that an escaped slash stays different
+	// from a real one
+	path := strings.TrimRight(normalizeEscapes(u.EscapedPath()), "/")
+	u.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return raw
+	}
+	u.RawPath = path
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	// Encode sorts by key, so parameter order doesn't matter either
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// Decodes percent-escapes of unreserved characters and upper-cases the rest,
+// so the same path escaped two ways compares equal (RFC 3986 6.2.2).
+func normalizeEscapes(escaped string) string {
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] == '%' && i+2 < len(escaped) {
+			if c, err := strconv.ParseUint(escaped[i+1:i+3], 16, 8); err == nil {
+				if unreserved(byte(c)) {
+					b.WriteByte(byte(c))
+				} else {
+					b.WriteString("%" + strings.ToUpper(escaped[i+1:i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(escaped[i])
+	}
+	return b.String()
+}
+
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// Reports whether two URLs point at the same host, port included. Stored
+// credentials are only ever sent to the host they were saved for.
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Largest request body we'll read
+const maxBodyBytes = 1 << 20
+
+const (
+	defaultPageSize = 10
+	// Bigger limits are clamped to this
+	maxPageSize = 100
+)
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Reads the JSON body into dst, rejecting unknown fields and anything over
+// maxBodyBytes, then checks the `validate` tags on dst's fields. An empty body
+// decodes as {} so required fields still get reported.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) *apiError {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("unexpected data after JSON object")
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return &apiError{Status: 413, Code: codeBodyTooLarge, Message: fmt.Sprintf("Request body larger than %d bytes", maxBodyBytes)}
+		}
+		return errInvalidBody(err)
+	}
+
+	if fields := validateStruct(dst); len(fields) > 0 {
+		return &apiError{Status: 400, Code: codeValidation, Message: "Request body failed validation", Fields: fields}
+	}
+
+	return nil
+}
+
+// Reads the ?limit= and ?offset= query parameters. Missing ones get the
+// defaults and a limit over maxPageSize is clamped, but anything that isn't a
+// whole number (at least 1 for limit) is a 400.
+func parsePage(r *http.Request) (limit, offset int32, apiErr *apiError) {
+	var fields []fieldError
+	parse := func(name string, value string, min int) int {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min {
+			fields = append(fields, fieldError{Field: name, Message: fmt.Sprintf("must be a whole number of at least %d", min)})
+		}
+		return n
+	}
+
+	limit, offset = defaultPageSize, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit = int32(min(parse("limit", v, 1), maxPageSize))
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset = int32(min(parse("offset", v, 0), math.MaxInt32))
+	}
+
+	if len(fields) > 0 {
+		return 0, 0, &apiError{Status: 400, Code: codeValidation, Message: "Invalid query parameters", Fields: fields}
+	}
+	return limit, offset, nil
+}
+
+// Checks every field with a `validate` tag and reports all failures at once.
+// Rules are comma separated:
+//
+//	required    must be set (and not blank, for strings)
+//	notblank    strings only, must not be empty or all whitespace
+//	min=N       strings: at least N characters, numbers: at least N, slices: at least N items
+//	max=N       the same, as an upper bound
+//	maxbytes=N  strings only, at most N bytes, e.g. for bcrypt's 72 byte limit
+//	url         an absolute http(s) URL
+//
+// Nil pointers skip every rule except required, so PATCH bodies can leave
+// fields out.
+func validateStruct(v any) []fieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []fieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = rt.Field(i).Name
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			if msg := checkRule(rv.Field(i), rule); msg != "" {
+				fields = append(fields, fieldError{Field: name, Message: msg})
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// Returns why the value breaks the rule, or "" if it doesn't
+func checkRule(value reflect.Value, rule string) string {
+	rule, arg, _ := strings.Cut(rule, "=")
+
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			if rule == "required" {
+				return "is required"
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch rule {
+	case "required":
+		if value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "") {
+			return "is required"
+		}
+	case "notblank":
+		if strings.TrimSpace(value.String()) == "" {
+			return "must not be blank"
+		}
+	case "min", "max":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validate: bad limit in rule " + rule + "=" + arg)
+		}
+
+		var n int
+		unit := ""
+		switch value.Kind() {
+		case reflect.String:
+			n = utf8.RuneCountInString(value.String())
+			unit = " characters"
+		case reflect.Slice:
+			n = value.Len()
+			unit = " items"
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n = int(value.Int())
+		default:
+			panic("validate: " + rule + " used on " + value.Kind().String())
+		}
+
+		if rule == "min" && n < limit {
+			return fmt.Sprintf("must be at least %d%s", limit, unit)
+		}
+		if rule == "max" && n > limit {
+			return fmt.Sprintf("must be at most %d%s", limit, unit)
+		}
+	case "maxbytes":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validate: bad limit in rule " + rule + "=" + arg)
+		}
+		if len(value.String()) > limit {
+			return fmt.Sprintf("must be at most %d bytes", limit)
+		}
+	case "url":
+		u, err := url.Parse(value.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an absolute http or https URL"
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+
+	return ""
+}
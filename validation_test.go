@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStruct(t *testing.T) {
+	type body struct {
+		Name     string   `json:"name" validate:"required,max=5"`
+		Note     string   `json:"note,omitempty" validate:"notblank"`
+		Password string   `json:"password" validate:"min=2,maxbytes=6"`
+		Url      string   `json:"url" validate:"url"`
+		Count    int      `json:"count" validate:"min=1,max=10"`
+		Tags     []string `json:"tags" validate:"max=2"`
+		Title    *string  `json:"title" validate:"max=3"`
+		Limit    *int     `validate:"required"`
+		Ignored  string   `json:"ignored"`
+	}
+
+	valid := func() body {
+		limit := 10
+		return body{
+			Name:     "feed",
+			Note:     "note",
+			Password: "secret",
+			Url:      "https://example.com/feed",
+			Count:    5,
+			Tags:     []string{"a"},
+			Limit:    &limit,
+		}
+	}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		modify func(b *body)
+		want   []fieldError
+	}{
+		{"valid", func(b *body) {}, nil},
+		{"required missing", func(b *body) { b.Name = "" }, []fieldError{{"name", "is required"}}},
+		{"required blank", func(b *body) { b.Name = "  " }, []fieldError{{"name", "is required"}}},
+		{"max characters", func(b *body) { b.Name = "feeds!" }, []fieldError{{"name", "must be at most 5 characters"}}},
+		{"max counts runes", func(b *body) { b.Name = "fééds" }, nil},
+		{"notblank", func(b *body) { b.Note = "\t" }, []fieldError{{"note", "must not be blank"}}},
+		{"min characters", func(b *body) { b.Password = "x" }, []fieldError{{"password", "must be at least 2 characters"}}},
+		{"maxbytes", func(b *body) { b.Password = "sécret" }, []fieldError{{"password", "must be at most 6 bytes"}}},
+		{"url relative", func(b *body) { b.Url = "/feed" }, []fieldError{{"url", "must be an absolute http or https URL"}}},
+		{"url scheme", func(b *body) { b.Url = "ftp://example.com/feed" }, []fieldError{{"url", "must be an absolute http or https URL"}}},
+		{"url no host", func(b *body) { b.Url = "https:///feed" }, []fieldError{{"url", "must be an absolute http or https URL"}}},
+		{"min number", func(b *body) { b.Count = 0 }, []fieldError{{"count", "must be at least 1"}}},
+		{"max number", func(b *body) { b.Count = 11 }, []fieldError{{"count", "must be at most 10"}}},
+		{"max items", func(b *body) { b.Tags = []string{"a", "b", "c"} }, []fieldError{{"tags", "must be at most 2 items"}}},
+		{"nil pointer skips rules", func(b *body) { b.Title = nil }, nil},
+		{"pointer checked", func(b *body) { b.Title = str("long") }, []fieldError{{"title", "must be at most 3 characters"}}},
+		{"nil pointer required", func(b *body) { b.Limit = nil }, []fieldError{{"Limit", "is required"}}},
+		{"first failing rule only", func(b *body) { b.Count = -1; b.Password = "" }, []fieldError{
+			{"password", "must be at least 2 characters"},
+			{"count", "must be at least 1"},
+		}},
+		{"every field reported", func(b *body) { b.Name = ""; b.Url = "nope" }, []fieldError{
+			{"name", "is required"},
+			{"url", "must be an absolute http or https URL"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := valid()
+			tt.modify(&b)
+			got := validateStruct(&b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateStruct() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStructBadRule(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"unknown rule", &struct {
+			Name string `validate:"shout"`
+		}{}, "unknown rule"},
+		{"bad limit", &struct {
+			Name string `validate:"max=five"`
+		}{}, "bad limit"},
+		{"max on a bool", &struct {
+			On bool `validate:"max=1"`
+		}{}, "max used on bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("validateStruct() panicked with %q, want %q", msg, tt.want)
+				}
+			}()
+			validateStruct(tt.v)
+		})
+	}
+}